@@ -0,0 +1,229 @@
+package gocco
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ## Section anchors
+
+// Every section keeps its numeric anchor, `#section-N`, so links made to
+// older builds still land, but N changes whenever a section is added
+// above, unless sections are anchored by their content, see
+// `sectionIDs`. Sections also get a named anchor: the id of the heading they
+// open with, or else a slug of the first words of their docs. Pages
+// link to sections with the named one.
+//
+// Names change too when docs are reworded, so the names of every page
+// are kept in `docs/anchors.json`. On the next build a name that is gone
+// is redirected to the name now in its place, and the page sends links
+// to the old name on to the new one. Sections are matched by position,
+// which holds up as long as a rewording doesn't come with sections
+// being added or removed
+
+// ### Section ids
+
+// The rows of a page are anchored by number, `section-N`. Teams that
+// commit the docs see every anchor below a new section change with it,
+// so with `-section-ids hash` the anchor comes from a digest of the
+// section as written instead, like `section-3fa2b1c0`, which only
+// changes with the section. Sections written alike are told apart by
+// their order, `section-3fa2b1c0-2`
+var sectionIDMode = "number"
+
+// check the value of `-section-ids`
+func setSectionIDs(value string) error {
+	switch value {
+	case "number", "hash":
+		sectionIDMode = value
+		return nil
+	}
+	return fmt.Errorf("unknown -section-ids %q, use number or hash", value)
+}
+
+// the digest of a section's docs and code, as far as anchors need
+func contentHash(docs, code []byte) string {
+	h := sha256.New()
+	h.Write(docs)
+	h.Write([]byte{0})
+	h.Write(code)
+	return hex.EncodeToString(h.Sum(nil)[:4])
+}
+
+// `sectionIDs` gives the anchors of a page's sections, in order
+func sectionIDs(sections *list.List) []string {
+	var ids []string
+	seen := make(map[string]int)
+	for e := sections.Front(); e != nil; e = e.Next() {
+		if sectionIDMode != "hash" {
+			ids = append(ids, fmt.Sprintf("section-%d", len(ids)+1))
+			continue
+		}
+		id := "section-" + e.Value.(*Section).hash
+		seen[id]++
+		if n := seen[id]; n > 1 {
+			id = fmt.Sprintf("%s-%d", id, n)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// a `sectionName` is the named anchor of a section, and whether it is
+// the id of the heading the section opens with, which needs no target
+// of its own
+type sectionName struct {
+	id      string
+	heading bool
+}
+
+// the rendered heading a section's docs open with
+var openingHeadingPattern = regexp.MustCompile(`^\s*<h[1-6] id="([^"]*)"`)
+
+// how many words of the docs make the name of a section without heading
+const anchorWords = 6
+
+// `nameSections` picks the named anchors of a page's sections, unique
+// among themselves and the ids of its headings. Sections without docs
+// only have their number
+func nameSections(sections *list.List, headings []*Heading) []sectionName {
+	used := make(map[string]bool)
+	for _, h := range headings {
+		used[h.ID] = true
+	}
+	var names []sectionName
+	for e := sections.Front(); e != nil; e = e.Next() {
+		section := e.Value.(*Section)
+		if m := openingHeadingPattern.FindSubmatch(section.DocsHTML); m != nil {
+			names = append(names, sectionName{string(m[1]), true})
+			continue
+		}
+		// the rendered docs are used so that the address of a link
+		// or markup doesn't end up in the name
+		text := strings.TrimSpace(html.UnescapeString(tagPattern.ReplaceAllString(string(section.DocsHTML), "")))
+		if i := strings.IndexByte(text, '\n'); i >= 0 {
+			text = text[:i]
+		}
+		words := strings.Fields(text)
+		if len(words) > anchorWords {
+			words = words[:anchorWords]
+		}
+		base := slugify(strings.Join(words, " "))
+		if base == "" {
+			names = append(names, sectionName{})
+			continue
+		}
+		id := base
+		for n := 1; used[id]; n++ {
+			id = fmt.Sprintf("%s-%d", base, n)
+		}
+		used[id] = true
+		names = append(names, sectionName{id, false})
+	}
+	return names
+}
+
+// a `PageAnchors` is what `docs/anchors.json` knows about a page: the
+// named anchors of its sections in order, and where names that are gone
+// lead to now
+type PageAnchors struct {
+	Anchors   []string          `json:"anchors"`
+	Redirects map[string]string `json:"redirects,omitempty"`
+}
+
+var (
+	previousAnchors = make(map[string]*PageAnchors)
+	currentAnchors  = make(map[string]*PageAnchors)
+	anchorsLock     sync.Mutex
+)
+
+// read the anchors of the last build, if there was one
+func loadAnchors() {
+	data, err := ioutil.ReadFile(filepath.Join(outputDir, "anchors.json"))
+	if os.IsNotExist(err) {
+		return
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &previousAnchors)
+	}
+	if err != nil {
+		warn(filepath.Join(outputDir, "anchors.json"), 0, "can't read the anchors of the last build: "+err.Error())
+	}
+}
+
+// `anchorRedirects` records the named anchors of `page` and works out
+// the redirects for the names its last build had and this one hasn't.
+// Redirects from earlier builds are carried along, following a name
+// that was renamed again
+func anchorRedirects(page string, names []sectionName) map[string]string {
+	ids := make([]string, len(names))
+	live := make(map[string]bool)
+	for i, name := range names {
+		ids[i] = name.id
+		if name.id != "" {
+			live[name.id] = true
+		}
+	}
+	anchorsLock.Lock()
+	defer anchorsLock.Unlock()
+	redirects := make(map[string]string)
+	if previous := previousAnchors[page]; previous != nil {
+		for i, old := range previous.Anchors {
+			if old != "" && !live[old] && i < len(ids) && ids[i] != "" {
+				redirects[old] = ids[i]
+			}
+		}
+		for old, target := range previous.Redirects {
+			if live[old] || redirects[old] != "" {
+				continue
+			}
+			if !live[target] {
+				target = redirects[target]
+			}
+			if target != "" {
+				redirects[old] = target
+			}
+		}
+	}
+	if len(redirects) == 0 {
+		redirects = nil
+	}
+	currentAnchors[page] = &PageAnchors{ids, redirects}
+	return redirects
+}
+
+// the redirects of a page as JSON, for its script to read
+func redirectsJSON(redirects map[string]string) string {
+	if len(redirects) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(redirects)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func writeAnchors() {
+	anchorsLock.Lock()
+	data, err := json.MarshalIndent(currentAnchors, "", "  ")
+	anchorsLock.Unlock()
+	if err != nil {
+		log.Println("gocco: ", err)
+		return
+	}
+	if err := writeFile(filepath.Join(outputDir, "anchors.json"), append(data, '\n')); err != nil {
+		log.Println("gocco: ", err)
+	}
+}
@@ -0,0 +1,72 @@
+package gocco
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// ## Annotations
+
+// On a continuous integration service the log is rarely read. With
+// `-annotations github` every warning and error is also printed as a
+// [workflow command](https://docs.github.com/actions/reference/workflow-commands-for-github-actions),
+// which GitHub Actions shows next to the offending line of a pull request
+var annotations string
+
+func setAnnotations(format string) error {
+	switch format {
+	case "", "github":
+		annotations = format
+		return nil
+	}
+	return fmt.Errorf("unknown annotation format %q, use github", format)
+}
+
+// GitHub reads the message up to the end of the line and the properties
+// up to the next comma, so those characters have to be escaped
+var (
+	messageEscaper  = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	propertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+// `annotate` reports a problem at `line` of `source`, 0 if it isn't
+// known, at `level` which is either "warning" or "error"
+func annotate(level, source string, line int, message string) {
+	if line > 0 {
+		log.Printf("gocco: %s:%d: %s", source, line, message)
+	} else {
+		log.Printf("gocco: %s: %s", source, message)
+	}
+	printAnnotation(level, source, line, message)
+}
+
+// print the workflow command of a problem for `-annotations`, if asked
+// for, without logging it
+func printAnnotation(level, source string, line int, message string) {
+	if annotations != "github" {
+		return
+	}
+	properties := "file=" + propertyEscaper.Replace(source)
+	if line > 0 {
+		properties += fmt.Sprintf(",line=%d", line)
+	}
+	fmt.Printf("::%s %s::%s\n", level, properties, messageEscaper.Replace(message))
+}
+
+// With `-strict` warnings count as errors: they are reported as such,
+// and make gocco exit with a failure status once it's done
+var strict bool
+
+// the number of warnings so far
+var warnings int32
+
+func warn(source string, line int, message string) {
+	atomic.AddInt32(&warnings, 1)
+	if strict {
+		annotate("error", source, line, message)
+		return
+	}
+	annotate("warning", source, line, message)
+}
@@ -0,0 +1,213 @@
+package gocco
+
+import (
+	"bytes"
+	"go/ast"
+	"go/doc"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ## API pages
+
+// The literate pages tell the story of a package in the order of its
+// files. With `-api` every Go package also gets a reference page,
+// `docs/api-<package>.html` or `api.html` in the package's directory
+// with `-by-package`, listing its exported identifiers with
+// their signatures and doc comments, each linking to the section of the
+// literate page where it's declared
+var apiPages bool
+
+// an `APIEntry` is one exported identifier
+type APIEntry struct {
+	Name      string
+	Signature string
+	Doc       string
+	// The section declaring the identifier
+	Href string
+	// The constructors and methods of a type
+	Funcs []*APIEntry
+}
+
+// an `APIPackage` holds the entries of one package, by kind
+type APIPackage struct {
+	Name      string
+	Dir       string
+	Page      string
+	Constants []*APIEntry
+	Variables []*APIEntry
+	Functions []*APIEntry
+	Types     []*APIEntry
+}
+
+// `lineSections` maps every line of a source, counted from 0, to the
+// section it ends up in, the way `parse` splits them
+func lineSections(source string, code []byte) []int {
+	language := getLanguage(source)
+	var sections []int
+	section, hasCode := 1, false
+	for _, line := range splitLines(language, code) {
+		if !line.docs {
+			hasCode = true
+		} else if hasCode {
+			section++
+			hasCode = false
+		}
+		sections = append(sections, section)
+	}
+	return sections
+}
+
+// `apiPackages` reads the Go sources package by package. Test files are
+// left out, they have no API to speak of
+func apiPackages() []*APIPackage {
+	byDir := make(map[string][]string)
+	for _, source := range sources {
+		if filepath.Ext(source) == ".go" && !strings.HasSuffix(source, "_test.go") {
+			dir := filepath.Dir(source)
+			byDir[dir] = append(byDir[dir], source)
+		}
+	}
+	var dirs []string
+	for dir := range byDir {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	var packages []*APIPackage
+	pages := make(map[string]bool)
+	for _, dir := range dirs {
+		fset := token.NewFileSet()
+		var files []*ast.File
+		sectionsOf := make(map[string][]string)
+		for _, source := range byDir[dir] {
+			code, err := readSource(source)
+			if err != nil {
+				inputError(err)
+				continue
+			}
+			file, err := parser.ParseFile(fset, source, code, parser.ParseComments)
+			if err != nil {
+				warn(source, 0, err.Error())
+				continue
+			}
+			files = append(files, file)
+			ids := sectionIDs(parse(source, code))
+			for _, section := range lineSections(source, code) {
+				if section-1 < len(ids) {
+					sectionsOf[source] = append(sectionsOf[source], ids[section-1])
+				}
+			}
+		}
+		if len(files) == 0 {
+			continue
+		}
+		p, err := doc.NewFromFiles(fset, files, filepath.ToSlash(dir))
+		if err != nil {
+			warn(dir, 0, err.Error())
+			continue
+		}
+		// packages of the same name in different directories are told
+		// apart by their directory
+		page := "api-" + p.Name + ".html"
+		if pages[page] {
+			page = "api-" + slugify(filepath.ToSlash(dir)) + ".html"
+		}
+		if byPackage {
+			page = filepath.ToSlash(filepath.Join(pageDirOf(dir), "api.html"))
+		}
+		pages[page] = true
+
+		// the link to the section declaring `node`
+		href := func(node ast.Node) string {
+			position := fset.Position(node.Pos())
+			link := relativeLink(filepath.Join(outputDir, page), destination(position.Filename))
+			if sections := sectionsOf[position.Filename]; joinName == "" && position.Line-1 < len(sections) {
+				link += "#" + sections[position.Line-1]
+			}
+			return link
+		}
+		entry := func(name string, decl ast.Node, text string) *APIEntry {
+			return &APIEntry{name, signature(fset, decl), string(command.markdown([]byte(text))), href(decl), nil}
+		}
+		pkg := &APIPackage{Name: p.Name, Dir: filepath.ToSlash(dir), Page: page}
+		for _, v := range p.Consts {
+			pkg.Constants = append(pkg.Constants, entry(strings.Join(v.Names, ", "), v.Decl, v.Doc))
+		}
+		for _, v := range p.Vars {
+			pkg.Variables = append(pkg.Variables, entry(strings.Join(v.Names, ", "), v.Decl, v.Doc))
+		}
+		for _, f := range p.Funcs {
+			pkg.Functions = append(pkg.Functions, entry(f.Name, f.Decl, f.Doc))
+		}
+		for _, t := range p.Types {
+			typ := entry(t.Name, t.Decl, t.Doc)
+			for _, f := range t.Funcs {
+				typ.Funcs = append(typ.Funcs, entry(f.Name, f.Decl, f.Doc))
+			}
+			// methods are named after their type, as in godoc
+			for _, f := range t.Methods {
+				typ.Funcs = append(typ.Funcs, entry(t.Name+"."+f.Name, f.Decl, f.Doc))
+			}
+			pkg.Types = append(pkg.Types, typ)
+		}
+		packages = append(packages, pkg)
+	}
+	return packages
+}
+
+// the declaration of an identifier as Go source, without its doc
+// comment or, for functions, its body
+func signature(fset *token.FileSet, decl ast.Node) string {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		copied := *d
+		copied.Doc, copied.Body = nil, nil
+		decl = &copied
+	case *ast.GenDecl:
+		copied := *d
+		copied.Doc = nil
+		decl = &copied
+	}
+	buf := new(bytes.Buffer)
+	printer.Fprint(buf, fset, decl)
+	return buf.String()
+}
+
+// the API page of each directory, worked out before any page is
+// rendered so that overviews can link to it
+var apiPageOf = make(map[string]string)
+
+func prepareAPIPages() []*APIPackage {
+	packages := apiPackages()
+	for _, pkg := range packages {
+		apiPageOf[filepath.FromSlash(pkg.Dir)] = pkg.Page
+	}
+	return packages
+}
+
+func writeAPIPages(packages []*APIPackage) {
+	for _, pkg := range packages {
+		html, err := renderTemplate("api", APIHTML, map[string]interface{}{
+			"Package":   pkg,
+			"SiteTitle": siteTitle,
+			"Index":     indexPage(),
+			"Root":      rootOf(filepath.Join(outputDir, pkg.Page)),
+			"Analytics": analytics,
+		})
+		dest := filepath.Join(outputDir, pkg.Page)
+		if err == nil {
+			err = writeFile(dest, html)
+		}
+		if err != nil {
+			renderFailure(err)
+			continue
+		}
+		log.Println("gocco: package ", pkg.Name, " -> ", dest)
+	}
+}
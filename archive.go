@@ -0,0 +1,134 @@
+package gocco
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// ## Archives
+
+// A release tarball or zip can be documented without unpacking it first:
+// `gocco project-1.0.tar.gz` reads the files inside, and each one is
+// known by its path in the archive. That path is what pages are named
+// and, with `-by-package`, laid out after
+var (
+	archived     = make(map[string][]byte)
+	archivedLock sync.Mutex
+)
+
+// whether an input is an archive, going by its name
+func isArchive(name string) bool {
+	for _, ext := range []string{".tar", ".tar.gz", ".tgz", ".zip"} {
+		if strings.HasSuffix(strings.ToLower(name), ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// remember the contents of a source that isn't a file on disk
+func keepSource(name string, data []byte) {
+	archivedLock.Lock()
+	archived[name] = data
+	archivedLock.Unlock()
+}
+
+// `readSource` reads a source from the archive or download it came from,
+// or from disk
+func readSource(source string) ([]byte, error) {
+	archivedLock.Lock()
+	code, ok := archived[source]
+	archivedLock.Unlock()
+	if ok {
+		return code, nil
+	}
+	return ioutil.ReadFile(source)
+}
+
+// `openArchive` reads the regular files of an archive into `archived`
+// and returns their names in the order they were stored. Entries
+// pointing outside the archive, like `../x` or `/etc/x`, are left out
+func openArchive(name string) ([]string, error) {
+	var names []string
+	add := func(entry string, r io.Reader) error {
+		clean := path.Clean(entry)
+		if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+			warn(name, 0, "skipping "+entry+", it points outside the archive")
+			return nil
+		}
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		keepSource(clean, data)
+		names = append(names, clean)
+		return nil
+	}
+
+	if strings.HasSuffix(strings.ToLower(name), ".zip") {
+		z, err := zip.OpenReader(name)
+		if err != nil {
+			return nil, err
+		}
+		defer z.Close()
+		for _, f := range z.File {
+			if !f.Mode().IsRegular() {
+				continue
+			}
+			r, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			err = add(f.Name, r)
+			r.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return names, nil
+	}
+
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var r io.Reader = file
+	// compressed or not, tarballs are told apart by their first bytes
+	// rather than their name
+	head := make([]byte, 2)
+	if n, _ := io.ReadFull(file, head); n == 2 && head[0] == 0x1f && head[1] == 0x8b {
+		gz, err := gzip.NewReader(io.MultiReader(bytes.NewReader(head), file))
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = io.MultiReader(bytes.NewReader(head[:n]), file)
+	}
+	t := tar.NewReader(r)
+	for {
+		header, err := t.Next()
+		if err == io.EOF {
+			return names, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := add(header.Name, t); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package gocco
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"log"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ## Images
+
+// Images in the docs are usually given relative to the source, which the
+// pages in `docs/` can't reach. They are copied over next to the pages,
+// or with `-inline-images` those up to that many bytes are embedded as
+// data URIs, so a page keeps working when passed around on its own
+var inlineImages int64
+
+var imgPattern = regexp.MustCompile(`<img src="([^"#:?]+)"`)
+
+// the assets copied so far, so that pages sharing one copy it once
+var copiedAssets = make(map[string]bool)
+var copiedAssetsLock sync.Mutex
+
+// `embedImages` inlines or copies the local images of a page
+func embedImages(source string, sections *list.List) {
+	for e := sections.Front(); e != nil; e = e.Next() {
+		section := e.Value.(*Section)
+		section.DocsHTML = imgPattern.ReplaceAllFunc(section.DocsHTML, func(match []byte) []byte {
+			src := string(imgPattern.FindSubmatch(match)[1])
+			if strings.HasPrefix(src, "/") {
+				return match
+			}
+			file := filepath.Join(filepath.Dir(section.source), filepath.FromSlash(src))
+			info, err := os.Stat(file)
+			if err != nil {
+				warn(section.source, 0, "missing image "+src)
+				return match
+			}
+			if info.Size() <= inlineImages {
+				if uri, err := dataURI(file); err == nil {
+					return []byte(`<img src="` + uri + `"`)
+				}
+			}
+			asset, err := copyAsset(file)
+			if err != nil {
+				log.Println("gocco: ", err)
+				return match
+			}
+			return []byte(`<img src="` + assetLink(source, asset) + `"`)
+		})
+	}
+}
+
+// encode a file as a data URI, typed by its extension
+func dataURI(file string) (string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	kind := mime.TypeByExtension(filepath.Ext(file))
+	if kind == "" {
+		kind = "application/octet-stream"
+	}
+	return "data:" + kind + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// copy a file into `docs/` at its path relative to the working
+// directory, or into `docs/assets/` when it lies outside of it. Files
+// from outside may share a name, so their copies are told apart by a
+// digest of their contents, `logo-1a2b3c4d.png`. The path of the copy
+// within `docs/` is returned
+func copyAsset(file string) (string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	asset := filepath.Clean(file)
+	if filepath.IsAbs(asset) || strings.HasPrefix(asset, "..") {
+		sum := sha256.Sum256(data)
+		name := filepath.Base(asset)
+		ext := filepath.Ext(name)
+		asset = filepath.Join("assets", strings.TrimSuffix(name, ext)+"-"+hex.EncodeToString(sum[:4])+ext)
+	}
+	copiedAssetsLock.Lock()
+	defer copiedAssetsLock.Unlock()
+	if copiedAssets[asset] {
+		return asset, nil
+	}
+	dest := filepath.Join(outputDir, asset)
+	if err := writeOutput(dest, file, data); err != nil {
+		return "", err
+	}
+	copiedAssets[asset] = true
+	return asset, nil
+}
+
+// the address of an asset in `docs/` relative to the page of `source`
+func assetLink(source, asset string) string {
+	return relativeLink(destination(source), filepath.Join(outputDir, asset))
+}
+
+// ## Raw sources
+
+// With `-raw` every source is copied next to its page and the header
+// links to the copy, so readers can grab the code the docs were made
+// from. Joined pages, which have no single source, go without
+var raw bool
+
+// where the copy of a source goes. A source that would overwrite its own
+// page, as `index.html` would, gets `.txt` added
+func rawDestination(source string) string {
+	dest := destination(source)
+	copy := filepath.Join(filepath.Dir(dest), filepath.Base(source))
+	if copy == dest {
+		copy += ".txt"
+	}
+	return copy
+}
+
+// the link from the page of `source` to its copy, empty without `-raw`
+func rawLink(source string) string {
+	if !raw || joinName != "" {
+		return ""
+	}
+	return relativeLink(destination(source), rawDestination(source))
+}
+
+func writeRaw(source string) error {
+	if rawLink(source) == "" {
+		return nil
+	}
+	code, err := readSource(source)
+	if err != nil {
+		return err
+	}
+	return writeOutput(rawDestination(source), source, code)
+}
+
+// ## Cache busting
+
+// Docs published at a `-base-url` usually sit behind a CDN that caches
+// stylesheets and scripts for long, so a new build could be shown with an
+// old `gocco.css`. There these files get the start of their SHA-256 in
+// their name, like `gocco.3f2a9c1b.css`, and the pages link to that
+var (
+	hashedAssets     = make(map[string]string)
+	hashedAssetsLock sync.Mutex
+)
+
+// the name under which the asset `name` was written
+func assetName(name string) string {
+	hashedAssetsLock.Lock()
+	defer hashedAssetsLock.Unlock()
+	if hashed, ok := hashedAssets[name]; ok {
+		return hashed
+	}
+	return name
+}
+
+// `writeAsset` writes a stylesheet or script shared by the pages into
+// `docs/`, under a hashed name when publishing to a base URL
+func writeAsset(name string, data []byte) error {
+	if baseURL != "" {
+		sum := sha256.Sum256(data)
+		ext := filepath.Ext(name)
+		hashed := strings.TrimSuffix(name, ext) + "." + hex.EncodeToString(sum[:4]) + ext
+		hashedAssetsLock.Lock()
+		hashedAssets[name] = hashed
+		hashedAssetsLock.Unlock()
+	}
+	return writeFile(filepath.Join(outputDir, assetName(name)), data)
+}
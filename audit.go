@@ -0,0 +1,187 @@
+package gocco
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ## Auditing pages
+
+// `gocco audit -a11y [dir]` reads the pages generated into `dir`,
+// `docs` by default, and reports what keeps them from being usable with
+// a screen reader or poor eyesight:
+//
+//   * images without alternative text, or with an empty one as
+//     markdown gives `![](chart.png)`
+//   * highlighted code whose colors contrast too little with the
+//     background of the code, for the style the page uses
+//   * pages without a language or without `main` and `banner` landmarks
+
+// WCAG asks for a contrast of 4.5 for normal text
+const minContrast = 4.5
+
+var (
+	imgTagPattern     = regexp.MustCompile(`<img\b[^>]*>`)
+	altPattern        = regexp.MustCompile(`\balt="[^"]*[^"\s]`)
+	srcPattern        = regexp.MustCompile(`\bsrc="([^"]*)"`)
+	stylesheetPattern = regexp.MustCompile(`<link rel="stylesheet"[^>]*href="([^":]+)"`)
+	inlineStyle       = regexp.MustCompile(`(?s)<style>(.*?)</style>`)
+	cssRulePattern    = regexp.MustCompile(`([^{}]+)\{([^}]*)\}`)
+	colorPattern      = regexp.MustCompile(`(?:^|;)\s*color:\s*#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})\b`)
+	backgroundPattern = regexp.MustCompile(`background(?:-color)?:\s*#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})\b`)
+	cssCommentPattern = regexp.MustCompile(`/\*.*?\*/`)
+	htmlLangPattern   = regexp.MustCompile(`<html[^>]*\blang="[^"]+"`)
+	tokenSelector     = regexp.MustCompile(`^(?:body|td\.code) (\.[a-z][a-z0-9]{0,2})$`)
+)
+
+func auditCommand(args []string) {
+	flags := flag.NewFlagSet("audit", flag.ExitOnError)
+	a11y := flags.Bool("a11y", false, "check the accessibility of the pages")
+	flags.Parse(args)
+	if !*a11y {
+		fail(exitConfig, "audit: nothing to check, use -a11y")
+	}
+	dir := outputDir
+	if flags.NArg() > 0 {
+		dir = flags.Arg(0)
+	}
+
+	var pages []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && filepath.Ext(path) == ".html" {
+			pages = append(pages, path)
+		}
+		return err
+	})
+	if err != nil {
+		fail(exitInput, err)
+	}
+	problems := 0
+	for _, page := range pages {
+		data, err := ioutil.ReadFile(page)
+		if err != nil {
+			fail(exitInput, err)
+		}
+		for _, problem := range auditPage(page, string(data)) {
+			fmt.Printf("%s: %s\n", page, problem)
+			problems++
+		}
+	}
+	if problems > 0 {
+		os.Exit(exitProblems)
+	}
+}
+
+// the accessibility problems of one page
+func auditPage(page, html string) []string {
+	var problems []string
+	for _, img := range imgTagPattern.FindAllString(html, -1) {
+		if !altPattern.MatchString(img) {
+			src := ""
+			if m := srcPattern.FindStringSubmatch(img); m != nil {
+				src = m[1]
+			}
+			if len(src) > 60 {
+				src = src[:60] + "…"
+			}
+			problems = append(problems, "image without alt text: "+src)
+		}
+	}
+	if !htmlLangPattern.MatchString(html) {
+		problems = append(problems, "no language given on <html>")
+	}
+	if !strings.Contains(html, "<main") && !strings.Contains(html, `role="main"`) {
+		problems = append(problems, "no main landmark")
+	}
+	if !strings.Contains(html, "<header") && !strings.Contains(html, `role="banner"`) {
+		problems = append(problems, "no banner landmark")
+	}
+	if strings.Contains(html, `class="code"`) {
+		problems = append(problems, auditContrast(page, html)...)
+	}
+	return problems
+}
+
+// `auditContrast` checks the colors of the highlighted tokens against
+// the background of the code. The stylesheets apply in the order the
+// page loads them, later rules winning
+func auditContrast(page, html string) []string {
+	var css []string
+	for _, m := range stylesheetPattern.FindAllStringSubmatch(html, -1) {
+		if data, err := ioutil.ReadFile(filepath.Join(filepath.Dir(page), filepath.FromSlash(m[1]))); err == nil {
+			css = append(css, string(data))
+		}
+	}
+	for _, m := range inlineStyle.FindAllStringSubmatch(html, -1) {
+		css = append(css, m[1])
+	}
+	background := "ffffff"
+	colors := make(map[string]string)
+	for _, sheet := range css {
+		for _, rule := range cssRulePattern.FindAllStringSubmatch(sheet, -1) {
+			for _, selector := range strings.Split(rule[1], ",") {
+				selector = strings.TrimSpace(cssCommentPattern.ReplaceAllString(selector, ""))
+				if selector == "td.code" {
+					if m := backgroundPattern.FindStringSubmatch(rule[2]); m != nil {
+						background = m[1]
+					}
+				}
+				if m := tokenSelector.FindStringSubmatch(selector); m != nil {
+					if c := colorPattern.FindStringSubmatch(rule[2]); c != nil {
+						colors[m[1]] = c[1]
+					}
+				}
+			}
+		}
+	}
+	var tokens []string
+	for token := range colors {
+		tokens = append(tokens, token)
+	}
+	sort.Strings(tokens)
+	var problems []string
+	for _, token := range tokens {
+		// whitespace has no visible text
+		if token == ".w" {
+			continue
+		}
+		if ratio := contrast(colors[token], background); ratio < minContrast {
+			problems = append(problems, fmt.Sprintf("code %s is #%s on #%s, a contrast of %.1f below %.1f",
+				token, colors[token], background, ratio, minContrast))
+		}
+	}
+	return problems
+}
+
+// the WCAG contrast ratio of two hex colors
+func contrast(a, b string) float64 {
+	la, lb := luminance(a), luminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// the relative luminance of a hex color, `rgb` or `rrggbb`
+func luminance(hex string) float64 {
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	channel := func(s string) float64 {
+		v, _ := strconv.ParseUint(s, 16, 8)
+		c := float64(v) / 255
+		if c <= 0.03928 {
+			return c / 12.92
+		}
+		return math.Pow((c+0.055)/1.055, 2.4)
+	}
+	return 0.2126*channel(hex[0:2]) + 0.7152*channel(hex[2:4]) + 0.0722*channel(hex[4:6])
+}
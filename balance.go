@@ -0,0 +1,57 @@
+package gocco
+
+import (
+	"html"
+	"strings"
+)
+
+// ## Balancing the columns
+
+// A short comment above a long stretch of code leaves the docs column
+// empty for most of the section, and a reader scrolling through the code
+// loses track of what it was about. With `-balance n` the first sentence
+// of the docs comes back, faded, next to every `n` lines of code. Folded
+// sections and `-sticky-docs`, which keeps the docs in view anyway, go
+// without. Library callers set `Balance` on their `Generator`
+var balanceLines int
+
+// a `Recap` is a repeat of a section's summary, `Top` pixels down its
+// docs cell
+type Recap struct {
+	Top  int
+	Text string
+}
+
+// code lines are 18px high, below the 14px padding of the code cell
+const (
+	codeLineHeight = 18
+	codeTop        = 14
+)
+
+// the recaps of a section with `lines` lines of code
+func (g *Generator) recaps(section *Section, lines int) []*Recap {
+	if g.Balance <= 0 || stickyDocs || lines <= g.Balance {
+		return nil
+	}
+	summary := summarize(string(section.DocsHTML))
+	if summary == "" {
+		return nil
+	}
+	var result []*Recap
+	for line := g.Balance; line < lines; line += g.Balance {
+		result = append(result, &Recap{codeTop + line*codeLineHeight, summary})
+	}
+	return result
+}
+
+// the first sentence of rendered docs as plain text, shortened if long
+func summarize(docs string) string {
+	text := strings.Join(strings.Fields(html.UnescapeString(tagPattern.ReplaceAllString(docs, " "))), " ")
+	if end := sentenceEnd.FindStringIndex(text); end != nil {
+		text = text[:end[0]+1]
+	}
+	if runes := []rune(text); len(runes) > 100 {
+		text = strings.TrimSpace(string(runes[:99])) + "…"
+	}
+	return text
+}
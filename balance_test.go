@@ -0,0 +1,85 @@
+package gocco
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name string
+		docs string
+		want string
+	}{
+		{"first sentence", "<p>Reads a file. Then closes it.</p>", "Reads a file."},
+		{"whitespace", "<p>Reads\n  a <em>file</em></p>", "Reads a file"},
+		{"entities", "<p>Runs <code>&lt;script&gt;</code> &amp; stops.</p>", "Runs <script> & stops."},
+		{"long", "<p>" + strings.Repeat("a ", 80) + "</p>", strings.TrimSpace(strings.Repeat("a ", 50)) + "…"},
+		{"empty", "<p></p>", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := summarize(test.docs); got != test.want {
+				t.Errorf("summarize(%q) = %q, want %q", test.docs, got, test.want)
+			}
+		})
+	}
+}
+
+func TestRecaps(t *testing.T) {
+	defer func(sticky bool) { stickyDocs = sticky }(stickyDocs)
+	section := &Section{DocsHTML: []byte("<p>Reads a file.</p>")}
+	tests := []struct {
+		name    string
+		balance int
+		sticky  bool
+		lines   int
+		want    []int
+	}{
+		{"off", 0, false, 40, nil},
+		{"short", 10, false, 10, nil},
+		{"every ten lines", 10, false, 35, []int{codeTop + 10*codeLineHeight, codeTop + 20*codeLineHeight, codeTop + 30*codeLineHeight}},
+		{"sticky docs", 10, true, 35, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g := NewGenerator()
+			g.Balance, stickyDocs = test.balance, test.sticky
+			var got []int
+			for _, recap := range g.recaps(section, test.lines) {
+				if recap.Text != "Reads a file." {
+					t.Errorf("recap text %q", recap.Text)
+				}
+				got = append(got, recap.Top)
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("recaps at %v, want %v", got, test.want)
+			}
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Errorf("recaps at %v, want %v", got, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRecapsEscaped(t *testing.T) {
+	if _, err := exec.LookPath("pygmentize"); err != nil {
+		t.Skip("no pygmentize")
+	}
+	g := NewGenerator("a.go")
+	g.Balance = 2
+	src := []byte("// Runs `<script>alert(1)</script>` first.\nfunc a() {\n\tb()\n\tc()\n\td()\n}\n")
+	page, err := g.Generate(src, Options{Filename: "a.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(page), `class="recap"`) {
+		t.Fatal("no recap on the page")
+	}
+	if strings.Contains(string(page), "<script>alert(1)") {
+		t.Error("recap text is not escaped")
+	}
+}
@@ -0,0 +1,94 @@
+package gocco
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ## Benchmarking
+
+// `gocco bench dir` documents every source under `dir`, found the way
+// `-recursive` finds them, a number of times and reports how long each
+// stage of the pipeline took, so the cost of a change can be compared
+// across versions:
+//
+//     $ gocco bench -n 10 .
+//     22 files, 10 runs
+//     stage         total    per run
+//     parse        31.2ms     3.12ms
+//     ...
+//
+// The stages are those of `prepareStages`, which every page goes through,
+// followed by the template. Pages and the images they copy are written
+// to a temporary directory, `docs/` is left alone
+var benchStages = []string{"parse", "directives", "highlight", "markdown", "template", "io"}
+
+func benchCommand(args []string) {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	runs := flags.Int("n", 5, "how many times to document the sources")
+	flags.Parse(args)
+	if flags.NArg() != 1 || *runs < 1 {
+		fail(exitConfig, "bench: give one directory, and -n of at least 1")
+	}
+
+	recursive = true
+	sources = collectSources(flags.Args())
+	if len(sources) == 0 {
+		fail(exitInput, "bench: no sources in ", flags.Arg(0))
+	}
+	commandSettings()
+	out, err := ioutil.TempDir("", "gocco-bench")
+	if err != nil {
+		fail(exitRender, err)
+	}
+	outputDir = out
+	// `fail` exits right away, without running deferred calls
+	abort := func(status int, v ...interface{}) {
+		os.RemoveAll(out)
+		fail(status, v...)
+	}
+	defer os.RemoveAll(out)
+
+	timings := make(map[string]time.Duration)
+	// `timed` runs a stage, adding its duration to the stage's total
+	timed := func(stage string, work func()) {
+		start := time.Now()
+		work()
+		timings[stage] += time.Since(start)
+	}
+	for run := 0; run < *runs; run++ {
+		for _, source := range sources {
+			var code []byte
+			timed("io", func() { code, err = readSource(source) })
+			if err != nil {
+				abort(exitInput, err)
+			}
+			sections, meta := command.prepareStages(source, code, timed)
+			var dest string
+			var html []byte
+			timed("template", func() { dest, html, err = command.renderPage(source, sections, meta) })
+			if err != nil {
+				abort(exitRender, err)
+			}
+			timed("io", func() { err = ioutil.WriteFile(filepath.Join(out, filepath.Base(dest)), html, fileMode) })
+			if err != nil {
+				abort(exitRender, err)
+			}
+		}
+	}
+
+	fmt.Printf("%d files, %d runs\n", len(sources), *runs)
+	fmt.Printf("%-10s %12s %12s\n", "stage", "total", "per run")
+	var total time.Duration
+	for _, stage := range benchStages {
+		total += timings[stage]
+		fmt.Printf("%-10s %12v %12v\n", stage, timings[stage].Round(time.Microsecond),
+			(timings[stage] / time.Duration(*runs)).Round(time.Microsecond))
+	}
+	fmt.Printf("%-10s %12v %12v\n", "all", total.Round(time.Microsecond),
+		(total / time.Duration(*runs)).Round(time.Microsecond))
+}
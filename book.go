@@ -0,0 +1,151 @@
+package gocco
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+)
+
+// ## Books
+
+// Alphabetical order rarely tells a story. A book, read with `-book`,
+// orders the sources into chapters grouped in parts, which the jump
+// menu, the previous and next links and the contents page follow:
+//
+//     {
+//       "title": "Inside gocco",
+//       "parts": [
+//         {"title": "Reading sources", "chapters": ["sources.go", "gocco.go"]},
+//         {"title": "Publishing", "chapters": ["site.go", "search.go"]}
+//       ]
+//     }
+//
+// Chapters are relative to the book file
+type Book struct {
+	Title string  `json:"title"`
+	Parts []*Part `json:"parts"`
+}
+
+// a `Part` is a titled group of chapters
+type Part struct {
+	Title    string   `json:"title"`
+	Chapters []string `json:"chapters"`
+}
+
+// the book in use, if any
+var book *Book
+
+// the part each chapter opens, by source
+var partStarts = make(map[string]string)
+
+func loadBook(file string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	book = new(Book)
+	if err := json.Unmarshal(data, book); err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	book.Title = expandEnv(file, book.Title)
+	dir := filepath.Dir(file)
+	for _, part := range book.Parts {
+		part.Title = expandEnv(file, part.Title)
+		for i, chapter := range part.Chapters {
+			part.Chapters[i] = filepath.Join(dir, filepath.FromSlash(expandEnv(file, chapter)))
+		}
+		if len(part.Chapters) > 0 && part.Title != "" {
+			partStarts[part.Chapters[0]] = part.Title
+		}
+	}
+	if siteTitle == "" {
+		siteTitle = book.Title
+	}
+	return nil
+}
+
+// `bookSources` lists the chapters of the book in order. Files given on
+// the command line as well must be part of the book
+func bookSources(args []string) []string {
+	var chapters []string
+	inBook := make(map[string]bool)
+	for _, part := range book.Parts {
+		for _, chapter := range part.Chapters {
+			chapters = append(chapters, chapter)
+			inBook[chapter] = true
+		}
+	}
+	for _, arg := range args {
+		if !inBook[filepath.Clean(arg)] {
+			warn(arg, 0, "not a chapter of the book, skipping")
+		}
+	}
+	return collectSources(chapters)
+}
+
+// `orderChapters` sorts the chapters of every part by their weights,
+// and the sources with them
+func orderChapters() {
+	if len(weights) == 0 {
+		return
+	}
+	position := make(map[string]int)
+	partStarts = make(map[string]string)
+	for _, part := range book.Parts {
+		sortByWeight(part.Chapters)
+		for _, chapter := range part.Chapters {
+			position[chapter] = len(position)
+		}
+		if len(part.Chapters) > 0 && part.Title != "" {
+			partStarts[part.Chapters[0]] = part.Title
+		}
+	}
+	sort.SliceStable(sources, func(i, j int) bool { return position[sources[i]] < position[sources[j]] })
+}
+
+// the title of the part a source opens, if any
+func partStarting(source string) string {
+	return partStarts[source]
+}
+
+// the contents page of the book, of the packages with `-by-package` or
+// of the files of the run, see `index.go`,
+// linked from every page's header relative to `docs/`
+func indexPage() string {
+	if book == nil && !byPackage && !command.shardedMenu() && !listsFiles() {
+		return ""
+	}
+	return "index.html"
+}
+
+// write the contents as `docs/index.html`
+func writeContents() {
+	var parts []*Part
+	if book != nil {
+		for _, part := range book.Parts {
+			parts = append(parts, &Part{part.Title, listedSources(part.Chapters)})
+		}
+	} else if byPackage || command.shardedMenu() {
+		parts = packageParts()
+	} else {
+		parts = indexParts()
+	}
+	html, err := renderTemplate("contents", ContentsHTML, map[string]interface{}{
+		"Title":     siteName(),
+		"Parts":     parts,
+		"Readme":    command.renderReadme("."),
+		"Analytics": analytics,
+	})
+	dest := filepath.Join(outputDir, "index.html")
+	if err == nil {
+		err = writeFile(dest, html)
+	}
+	if err != nil {
+		renderFailure(err)
+		return
+	}
+	log.Println("gocco: contents -> ", dest)
+}
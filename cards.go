@@ -0,0 +1,114 @@
+package gocco
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// ## Social cards
+
+// A link to a page shared in a chat or on social media is previewed with
+// the image its `og:image` tag names. With `-social-cards` every page
+// gets one, a PNG with the project's name, the page's title and the
+// path of its source, in `docs/cards/`. The tags need the absolute
+// address of the card, so cards are only linked when `-base-url` is set
+var socialCards bool
+
+// the size social networks ask for
+const cardWidth, cardHeight = 1200, 630
+
+var (
+	cardBackground = color.RGBA{0x25, 0x28, 0x2d, 0xff}
+	cardText       = color.RGBA{0xf5, 0xf5, 0xff, 0xff}
+	cardMuted      = color.RGBA{0x9a, 0xa0, 0xa8, 0xff}
+)
+
+// the location of the card of a source's page, relative to `docs/`
+func cardPath(source string) string {
+	return "cards/" + strings.TrimSuffix(pagePath(source), ".html") + ".png"
+}
+
+// `drawCard` renders a card. The text uses the bitmap face that comes
+// with x/image, scaled up, so no font has to be shipped
+func drawCard(project, title, path string) []byte {
+	card := image.NewRGBA(image.Rect(0, 0, cardWidth, cardHeight))
+	draw.Draw(card, card.Bounds(), &image.Uniform{cardBackground}, image.Point{}, draw.Src)
+	drawText(card, project, 80, 90, 4, cardMuted)
+	for i, line := range wrapText(title, (cardWidth-160)/(7*8), 3) {
+		drawText(card, line, 80, 230+i*120, 8, cardText)
+	}
+	drawText(card, path, 80, cardHeight-80, 4, cardMuted)
+	buf := new(bytes.Buffer)
+	png.Encode(buf, card)
+	return buf.Bytes()
+}
+
+// draw `text` with its top left corner at `x`, `y`, every pixel of the
+// face becoming a `scale` by `scale` square
+func drawText(dst *image.RGBA, text string, x, y, scale int, c color.Color) {
+	face := basicfont.Face7x13
+	width := font.MeasureString(face, text).Ceil()
+	if max := (cardWidth - x) / scale; width > max {
+		width = max
+	}
+	small := image.NewAlpha(image.Rect(0, 0, width, face.Height))
+	drawer := &font.Drawer{Dst: small, Src: image.Opaque, Face: face, Dot: fixed.P(0, face.Ascent)}
+	drawer.DrawString(text)
+	for sy := 0; sy < face.Height; sy++ {
+		for sx := 0; sx < width; sx++ {
+			if small.AlphaAt(sx, sy).A < 0x80 {
+				continue
+			}
+			r := image.Rect(x+sx*scale, y+sy*scale, x+(sx+1)*scale, y+(sy+1)*scale)
+			draw.Draw(dst, r, &image.Uniform{c}, image.Point{}, draw.Src)
+		}
+	}
+}
+
+// break `text` into at most `lines` lines of `width` characters, the
+// last one cut short with an ellipsis if it doesn't fit
+func wrapText(text string, width, lines int) []string {
+	var wrapped []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		switch {
+		case line == "":
+			line = word
+		case len(line)+1+len(word) <= width:
+			line += " " + word
+		default:
+			wrapped = append(wrapped, line)
+			line = word
+		}
+	}
+	wrapped = append(wrapped, line)
+	if len(wrapped) > lines {
+		wrapped = wrapped[:lines]
+		wrapped[lines-1] += " ..."
+	}
+	for i, l := range wrapped {
+		if len(l) > width {
+			wrapped[i] = l[:width-3] + "..."
+		}
+	}
+	return wrapped
+}
+
+// write the card of a page, returning its address for `og:image`
+func writeCard(source, title string) string {
+	dest := filepath.Join(outputDir, filepath.FromSlash(cardPath(source)))
+	if err := writeOutput(dest, source, drawCard(siteName(), title, filepath.ToSlash(source))); err != nil {
+		renderFailure(err)
+		return ""
+	}
+	return siteURL(cardPath(source))
+}
@@ -0,0 +1,10 @@
+// The gocco command generates literate documentation for source files
+// into `docs/`. Everything it does lives in the gocco package, see there
+// for the flags and subcommands.
+package main
+
+import "github.com/nikhilm/gocco"
+
+func main() {
+	gocco.Main()
+}
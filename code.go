@@ -0,0 +1,98 @@
+package gocco
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ## Formatting the code
+
+// How the code column is put together can be tuned in the `code` part of
+// the configuration:
+//
+//     "code": {
+//       "tab_width": 4,
+//       "line_numbers": true,
+//       "no_pre": false,
+//       "wrap_lines": true
+//     }
+//
+// They apply whether Pygments or the browser highlights the code
+type CodeOptions struct {
+	// Show tabs as this many columns, zero keeps the tabs
+	TabWidth int `json:"tab_width"`
+	// Put the numbers of the lines in a column of their own next to the
+	// code, like the table mode of Pygments' `linenos`, counting the
+	// lines of the source rather than of the section
+	LineNumbers bool `json:"line_numbers"`
+	// Leave out the `<div class="highlight"><pre>` around the code of a
+	// section, for templates that bring their own
+	NoPre bool `json:"no_pre"`
+	// Break lines too long for the column instead of scrolling them
+	WrapLines bool `json:"wrap_lines"`
+}
+
+func (o *CodeOptions) check() error {
+	if o.TabWidth < 0 {
+		return fmt.Errorf("tab_width is %d, use 0 or more", o.TabWidth)
+	}
+	return nil
+}
+
+// the options passed to Pygments' HTML formatter
+func (g *Generator) pygmentsOptions() string {
+	options := "encoding=utf-8"
+	if g.Code.TabWidth > 0 {
+		options += ",tabsize=" + strconv.Itoa(g.Code.TabWidth)
+	}
+	return options
+}
+
+// `formatCode` wraps the highlighted code of every section as the `code`
+// options ask
+func (g *Generator) formatCode(sections *list.List) {
+	options := g.Code
+	for e := sections.Front(); e != nil; e = e.Next() {
+		section := e.Value.(*Section)
+		code := section.CodeHTML
+		inner := bytes.TrimSuffix(bytes.TrimPrefix(code, []byte(highlightStart)), []byte(highlightEnd))
+		switch {
+		case options.NoPre:
+			code = inner
+		case options.WrapLines:
+			code = []byte(`<div class="highlight wrap-lines"><pre>` + string(inner) + highlightEnd)
+		}
+		if options.LineNumbers && !options.NoPre && len(section.codeText) > 0 {
+			code = []byte(`<table class="highlighttable"><tr><td class="linenos"><pre>` +
+				lineNumbers(section, e.Next() == nil) + `</pre></td><td>` + string(code) + `</td></tr></table>`)
+		}
+		section.CodeHTML = code
+	}
+}
+
+// the numbers of the lines of a section's code, one per line. The code
+// runs up to the end of the section, but the blank lines it ends with
+// aren't shown. The code of the `last` section of a source ending with a
+// newline has an empty line after that end
+func lineNumbers(section *Section, last bool) string {
+	count := func(text []byte) int {
+		n := bytes.Count(text, []byte("\n"))
+		if len(text) > 0 && !bytes.HasSuffix(text, []byte("\n")) {
+			n++
+		}
+		return n
+	}
+	lines := count(section.codeText)
+	if last && (bytes.HasSuffix(section.codeText, []byte("\n\n")) || string(section.codeText) == "\n") {
+		lines--
+	}
+	first := section.end - lines + 1
+	numbers := make([]string, count(bytes.TrimRight(section.codeText, "\n")))
+	for i := range numbers {
+		numbers[i] = strconv.Itoa(first + i)
+	}
+	return strings.Join(numbers, "\n")
+}
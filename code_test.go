@@ -0,0 +1,70 @@
+package gocco
+
+import (
+	"container/list"
+	"os/exec"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestFormatCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		options CodeOptions
+		want    string
+	}{
+		{"default", CodeOptions{}, highlightStart + "a()\nb()" + highlightEnd},
+		{"no pre", CodeOptions{NoPre: true}, "a()\nb()"},
+		{"wrapped", CodeOptions{WrapLines: true}, `<div class="highlight wrap-lines"><pre>a()` + "\nb()" + highlightEnd},
+		{"line numbers", CodeOptions{LineNumbers: true},
+			`<table class="highlighttable"><tr><td class="linenos"><pre>4` + "\n5" + `</pre></td><td>` +
+				highlightStart + "a()\nb()" + highlightEnd + `</td></tr></table>`},
+		{"line numbers without pre", CodeOptions{LineNumbers: true, NoPre: true}, "a()\nb()"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sections := list.New()
+			sections.PushBack(&Section{codeText: []byte("a()\nb()\n"), CodeHTML: []byte(highlightStart + "a()\nb()" + highlightEnd), end: 5})
+			sections.PushBack(&Section{})
+			g := NewGenerator()
+			g.Code = test.options
+			g.formatCode(sections)
+			if got := string(sections.Front().Value.(*Section).CodeHTML); got != test.want {
+				t.Errorf("code is %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+// the copy button and the fold script take the code of a cell from its
+// `.highlight pre`, which mustn't be the column of line numbers
+func TestLineNumbersMarkup(t *testing.T) {
+	if _, err := exec.LookPath("pygmentize"); err != nil {
+		t.Skip("no pygmentize")
+	}
+	if strings.Count(Js, `cells[i].querySelector(".highlight pre")`) != 2 {
+		t.Fatal("the copy and fold scripts don't select the .highlight pre of a cell")
+	}
+	g := NewGenerator("a.go")
+	g.Code.LineNumbers = true
+	page, err := g.Generate([]byte("// Reads.\nfunc Read() {\n\tread()\n}\n"), Options{Filename: "a.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cell := regexp.MustCompile(`(?s)<td class="code"[^>]*>(.*?)</td>\s*</tr>\s*</table>`).FindSubmatch(page)
+	if cell == nil {
+		t.Fatalf("no code cell with line numbers in %s", page)
+	}
+	if !strings.Contains(string(cell[1]), `<td class="linenos"><pre>2`+"\n3\n4</pre>") {
+		t.Errorf("no line numbers in %s", cell[1])
+	}
+	pre := regexp.MustCompile(`(?s)<div class="highlight"><pre>(.*?)</pre>`).FindSubmatch(cell[1])
+	if pre == nil {
+		t.Fatalf("no .highlight pre in %s", cell[1])
+	}
+	code := tagPattern.ReplaceAllString(string(pre[1]), "")
+	if !strings.Contains(code, "func Read()") || strings.Contains(code, "2\n3") {
+		t.Errorf(".highlight pre holds %q, want the code alone", code)
+	}
+}
@@ -0,0 +1,147 @@
+package gocco
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+)
+
+// ## Block comments
+
+// Besides line comments, docs can be written in block comments that
+// start a line: C's `/* */`, Python's docstrings, HTML's `<!-- -->` and
+// their kin. Each line inside becomes a line of docs, with the ` * `
+// many C programmers start them with taken off. A block comment followed
+// by code on the line it ends stays code
+var blockComments = map[string][2]string{
+	"c":            {"/*", "*/"},
+	"cpp":          {"/*", "*/"},
+	"objective-c":  {"/*", "*/"},
+	"csharp":       {"/*", "*/"},
+	"d":            {"/*", "*/"},
+	"go":           {"/*", "*/"},
+	"java":         {"/*", "*/"},
+	"kotlin":       {"/*", "*/"},
+	"scala":        {"/*", "*/"},
+	"groovy":       {"/*", "*/"},
+	"swift":        {"/*", "*/"},
+	"rust":         {"/*", "*/"},
+	"dart":         {"/*", "*/"},
+	"php":          {"/*", "*/"},
+	"protobuf":     {"/*", "*/"},
+	"verilog":      {"/*", "*/"},
+	"javascript":   {"/*", "*/"},
+	"typescript":   {"/*", "*/"},
+	"sql":          {"/*", "*/"},
+	"css":          {"/*", "*/"},
+	"python":       {`"""`, `"""`},
+	"coffeescript": {"###", "###"},
+	"ruby":         {"=begin", "=end"},
+	"lua":          {"--[[", "]]"},
+	"haskell":      {"{-", "-}"},
+	"elm":          {"{-", "-}"},
+	"julia":        {"#=", "=#"},
+	"nim":          {"#[", "]#"},
+	"fsharp":       {"(*", "*)"},
+	"powershell":   {"<#", "#>"},
+	"html":         {"<!--", "-->"},
+	"xml":          {"<!--", "-->"},
+}
+
+// a `sourceLine` is a line of a source, told apart as docs or code. The
+// text of docs is without the comment markers
+type sourceLine struct {
+	text []byte
+	docs bool
+}
+
+// the ` * ` starting the lines of a C style block comment
+var blockDecoration = regexp.MustCompile(`^\s*\*+ ?`)
+
+// `splitLines` tells the docs of a source from its code, line by line,
+// giving one `sourceLine` for every line. `parse` and everything
+// counting sections the way it does go by this
+func splitLines(language *Language, code []byte) []*sourceLine {
+	var lines []*sourceLine
+	start, end := []byte(language.blockStart), []byte(language.blockEnd)
+	cStyle := language.blockStart == "/*"
+	if len(start) > 0 {
+		start = append(start, docMarker...)
+	}
+	// inside a block comment, and the indentation it started at
+	inBlock, indent := false, []byte(nil)
+	docs := func(text []byte) {
+		if cStyle {
+			text = blockDecoration.ReplaceAll(text, nil)
+		} else {
+			text = bytes.TrimPrefix(text, indent)
+		}
+		lines = append(lines, &sourceLine{bytes.TrimRight(text, " \t"), true})
+	}
+
+	for _, line := range bytes.Split(code, []byte("\n")) {
+		trimmed := bytes.TrimLeft(line, " \t")
+		switch {
+		case inBlock:
+			i := bytes.Index(line, end)
+			if i < 0 {
+				docs(line)
+				continue
+			}
+			inBlock = false
+			if len(bytes.TrimSpace(line[i+len(end):])) > 0 {
+				lines = append(lines, &sourceLine{line, false})
+				continue
+			}
+			docs(line[:i])
+		case len(start) > 0 && bytes.HasPrefix(trimmed, start):
+			rest := trimmed[len(start):]
+			i := bytes.Index(rest, end)
+			if i >= 0 && len(bytes.TrimSpace(rest[i+len(end):])) > 0 {
+				lines = append(lines, &sourceLine{line, false})
+				continue
+			}
+			indent = line[:len(line)-len(trimmed)]
+			if i < 0 {
+				inBlock = true
+			} else {
+				rest = rest[:i]
+			}
+			// `/**` leaves stars behind
+			if cStyle {
+				rest = bytes.TrimLeft(rest, "*")
+			}
+			lines = append(lines, &sourceLine{bytes.TrimSpace(rest), true})
+		case language.commentMatcher != nil && language.commentMatcher.Match(line):
+			lines = append(lines, &sourceLine{language.commentMatcher.ReplaceAll(line, nil), true})
+		default:
+			lines = append(lines, &sourceLine{line, false})
+		}
+	}
+	return lines
+}
+
+// ## Doc markers
+
+// Some teams keep narrative comments apart from notes on the
+// implementation. With `-doc-marker` only comments starting with the
+// marker right after the comment symbol become docs, like `//:` or `#:`
+// for a marker of `:`, and `/*:` for block comments. Every other comment
+// stays with the code
+var docMarker string
+
+// use `marker` as the doc marker of every language
+func setDocMarker(marker string) {
+	docMarker = marker
+	for _, lang := range languages {
+		prepareLanguage(lang)
+	}
+}
+
+// the divider of a language without line comments, a block comment
+// Pygments renders as a comment or a multiline one
+func blockDivider(language *Language) (string, *regexp.Regexp) {
+	divider := language.blockStart + "DIVIDER" + language.blockEnd
+	return "\n" + divider + "\n",
+		regexp.MustCompile("\\n*<span class=\"cm?\">" + regexp.QuoteMeta(html.EscapeString(divider)) + "</span>\\n*")
+}
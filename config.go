@@ -0,0 +1,207 @@
+package gocco
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// ## Project configuration
+
+// Settings a project wants every run to share go into `gocco.json`, next
+// to where gocco runs, or the file given with `-config`:
+//
+//     {
+//       "sources": ["main.go", "cmd", "internal/*/*.go"],
+//       "exclude": ["vendor", "*_test.go"],
+//       "output": "site",
+//       "style": "monokai",
+//       "languages": {
+//         ".tmpl": {"name": "go-html-template", "symbol": "//"}
+//       },
+//       "templates": {"gocco": "templates/page.html"},
+//       "css": "templates/site.css",
+//       "markdown": {
+//         "engine": "goldmark",
+//         "hard_wraps": true,
+//         "raw_html": "skip",
+//         "heading_ids": "github",
+//         "link_target": "blank",
+//         "raw_blocks": true,
+//         "heading_offset": 1
+//       },
+//       "code": {"tab_width": 4, "line_numbers": true}
+//     }
+//
+// The sources are documented when none are given on the command line:
+// files, directories, which are searched like with `-recursive`, and
+// globs. Sources matching one of the `exclude` patterns are left out,
+// whether by their path, their name or one of their directories. The
+// output directory gives way to `-output`, and the Pygments style to
+// `-style` and a page's own. Languages are added or replaced by
+// extension, and templates by the names `renderTemplate` knows them by,
+// with the file to read each from. The `css` file replaces `gocco.css`,
+// see `template.go`, and `code` is described in `code.go`
+type Config struct {
+	Sources   []string                   `json:"sources"`
+	Exclude   []string                   `json:"exclude"`
+	Output    string                     `json:"output"`
+	Style     string                     `json:"style"`
+	Languages map[string]*LanguageConfig `json:"languages"`
+	Templates map[string]string          `json:"templates"`
+	CSS       string                     `json:"css"`
+	Markdown  MarkdownOptions            `json:"markdown"`
+	Code      CodeOptions                `json:"code"`
+}
+
+// a `LanguageConfig` is a language of the configuration: its Pygments
+// name and the symbol starting its line comments, empty when it only has
+// block comments
+type LanguageConfig struct {
+	Name   string `json:"name"`
+	Symbol string `json:"symbol"`
+}
+
+// the templates that can be replaced, and what they render
+var templateNames = map[string]string{
+	"gocco":      "the page of a source",
+	"contents":   "the table of contents",
+	"overview":   "the index page of a directory",
+	"api":        "the reference page of a package",
+	"search":     "the search page",
+	"opensearch": "the OpenSearch description",
+	"404":        "the page for addresses that don't exist",
+	"heatmap":    "the heatmap",
+	"stats":      "the table of statistics",
+	"changes":    "the changes since the last build",
+	"fragment":   "the sections sent to an editor",
+}
+
+// the text of the templates replaced by the configuration, by name
+var templateOverrides = make(map[string]string)
+
+// the configuration in effect
+var config = &Config{Markdown: defaultMarkdown}
+
+// read the configuration, keeping the defaults when the file is missing
+// and not `required`
+func loadConfig(file string, required bool) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		if required || !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	loaded := &Config{Markdown: defaultMarkdown}
+	if err := json.Unmarshal(data, loaded); err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	if err := loaded.Markdown.check(); err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	if err := loaded.Code.check(); err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	for _, pattern := range loaded.Exclude {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%s: exclude %q: %v", file, pattern, err)
+		}
+	}
+	for ext, language := range loaded.Languages {
+		if !strings.HasPrefix(ext, ".") || language == nil || language.Name == "" {
+			return fmt.Errorf("%s: language %q needs an extension starting with . and a name", file, ext)
+		}
+		if language.Symbol == "" && blockComments[language.Name][0] == "" {
+			return fmt.Errorf("%s: language %q has no comments gocco knows, give a symbol", file, ext)
+		}
+	}
+	for name, path := range loaded.Templates {
+		if _, ok := templateNames[name]; !ok {
+			return fmt.Errorf("%s: there is no template %q to replace", file, name)
+		}
+		text, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("%s: template %s: %v", file, name, err)
+		}
+		if _, err := template.New(name).Funcs(templateFuncs).Parse(string(text)); err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+		templateOverrides[name] = string(text)
+	}
+	if loaded.CSS != "" {
+		if err := setStyleSheet(loaded.CSS); err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+	}
+	config = loaded
+	return nil
+}
+
+// `applyConfig` puts the configuration into effect. Settings with a
+// flag of their own only apply when the flag isn't given
+func applyConfig() {
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	if config.Output != "" && !given["output"] && !given["o"] {
+		outputDir = config.Output
+	}
+	if config.Style != "" && !given["style"] {
+		codeStyle = config.Style
+	}
+	for ext, language := range config.Languages {
+		block := blockComments[language.Name]
+		lang := &Language{language.Name, language.Symbol, block[0], block[1], nil, "", nil}
+		prepareLanguage(lang)
+		languages[strings.ToLower(ext)] = lang
+	}
+}
+
+// the sources of the configuration, with globs expanded
+func configSources() []string {
+	var args []string
+	for _, source := range config.Sources {
+		if !strings.ContainsAny(source, "*?[") {
+			args = append(args, source)
+			continue
+		}
+		matches, err := filepath.Glob(source)
+		if err != nil {
+			inputError(fmt.Errorf("%s: %v", source, err))
+			continue
+		}
+		if len(matches) == 0 {
+			warn(source, 0, "matches no files")
+		}
+		args = append(args, matches...)
+	}
+	return args
+}
+
+// whether a source is left out by the `exclude` patterns of the
+// configuration, by its path, its name or any of its directories
+func excluded(path string) bool {
+	if len(config.Exclude) == 0 {
+		return false
+	}
+	path = filepath.ToSlash(filepath.Clean(path))
+	parts := strings.Split(path, "/")
+	for _, pattern := range config.Exclude {
+		pattern = strings.TrimSuffix(filepath.ToSlash(pattern), "/")
+		for i := range parts {
+			// the path so far, and the part on its own
+			if ok, _ := filepath.Match(pattern, strings.Join(parts[:i+1], "/")); ok {
+				return true
+			}
+			if ok, _ := filepath.Match(pattern, parts[i]); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
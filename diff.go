@@ -0,0 +1,136 @@
+package gocco
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ## Changes between builds
+
+// Before a build replaces `docs/manifest.json` the old one is kept as
+// `docs/manifest.previous.json`. `gocco diff-output [-html] [dir]`
+// compares the two and lists the pages that were added, changed or
+// removed, which shows reviewers what a code change does to the docs.
+// With `-html` the list also goes to `changes.html` in `dir`
+
+// a `PageChange` is one line of the comparison
+type PageChange struct {
+	Path   string
+	Source string
+}
+
+// read the entries of a manifest by path
+func readManifest(file string) (map[string]*ManifestEntry, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var m struct {
+		Files []*ManifestEntry `json:"files"`
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	entries := make(map[string]*ManifestEntry)
+	for _, entry := range m.Files {
+		entries[entry.Path] = entry
+	}
+	return entries, nil
+}
+
+// keep the manifest of the last build before writing a new one
+func keepPreviousManifest() {
+	current := filepath.Join(outputDir, "manifest.json")
+	if _, err := os.Stat(current); err == nil {
+		if err := os.Rename(current, filepath.Join(outputDir, "manifest.previous.json")); err != nil {
+			log.Println("gocco: ", err)
+		}
+	}
+}
+
+// `compareManifests` sorts the paths of two builds into added, changed
+// and removed ones
+func compareManifests(previous, current map[string]*ManifestEntry) (added, changed, removed []*PageChange) {
+	for path, entry := range current {
+		before, ok := previous[path]
+		switch {
+		case !ok:
+			added = append(added, &PageChange{path, entry.Source})
+		case before.SHA256 != entry.SHA256:
+			changed = append(changed, &PageChange{path, entry.Source})
+		}
+	}
+	for path, entry := range previous {
+		if _, ok := current[path]; !ok {
+			removed = append(removed, &PageChange{path, entry.Source})
+		}
+	}
+	for _, changes := range [][]*PageChange{added, changed, removed} {
+		sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	}
+	return
+}
+
+func diffOutputCommand(args []string) {
+	flags := flag.NewFlagSet("diff-output", flag.ExitOnError)
+	page := flags.Bool("html", false, "also write the changes to changes.html")
+	flags.Parse(args)
+	dir := outputDir
+	if flags.NArg() > 0 {
+		dir = flags.Arg(0)
+	}
+
+	previous, err := readManifest(filepath.Join(dir, "manifest.previous.json"))
+	if err != nil {
+		fail(exitInput, "diff-output: no earlier build to compare with: ", err)
+	}
+	current, err := readManifest(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		fail(exitInput, "diff-output: ", err)
+	}
+	added, changed, removed := compareManifests(previous, current)
+	for _, group := range []struct {
+		label   string
+		changes []*PageChange
+	}{{"added", added}, {"changed", changed}, {"removed", removed}} {
+		for _, change := range group.changes {
+			fmt.Printf("%-8s %s\n", group.label, change.Path)
+		}
+	}
+
+	if *page {
+		html, err := renderTemplate("changes", ChangesHTML, map[string]interface{}{
+			"Title":   siteName(),
+			"Added":   added,
+			"Changed": changed,
+			"Removed": removed,
+		})
+		if err != nil {
+			fail(exitRender, err)
+		}
+		// the page joins the build it describes, so it's added to its
+		// manifest, and to its checksums if it has them
+		outputDir = dir
+		for _, entry := range current {
+			manifest[filepath.FromSlash(entry.Path)] = entry
+		}
+		dest := filepath.Join(dir, "changes.html")
+		if err := writeOutput(dest, "", html); err != nil {
+			fail(exitRender, err)
+		}
+		log.Println("gocco: changes -> ", dest)
+		saveManifest()
+		if _, err := os.Stat(filepath.Join(dir, "SHA256SUMS")); err == nil {
+			writeChecksums()
+		}
+		if exitStatus() != 0 {
+			exit()
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package gocco
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareManifests(t *testing.T) {
+	entry := func(path, sum string) *ManifestEntry {
+		return &ManifestEntry{Path: path, Source: path + ".go", SHA256: sum}
+	}
+	manifest := func(entries ...*ManifestEntry) map[string]*ManifestEntry {
+		m := make(map[string]*ManifestEntry)
+		for _, e := range entries {
+			m[e.Path] = e
+		}
+		return m
+	}
+	change := func(path string) *PageChange { return &PageChange{path, path + ".go"} }
+	tests := []struct {
+		name                    string
+		previous, current       map[string]*ManifestEntry
+		added, changed, removed []*PageChange
+	}{
+		{"same", manifest(entry("a", "1")), manifest(entry("a", "1")), nil, nil, nil},
+		{"added", manifest(entry("a", "1")), manifest(entry("c", "3"), entry("a", "1"), entry("b", "2")),
+			[]*PageChange{change("b"), change("c")}, nil, nil},
+		{"changed", manifest(entry("a", "1"), entry("b", "2")), manifest(entry("a", "1"), entry("b", "3")),
+			nil, []*PageChange{change("b")}, nil},
+		{"removed", manifest(entry("a", "1"), entry("b", "2")), manifest(entry("b", "2")),
+			nil, nil, []*PageChange{change("a")}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			added, changed, removed := compareManifests(test.previous, test.current)
+			for _, got := range []struct {
+				label     string
+				got, want []*PageChange
+			}{{"added", added, test.added}, {"changed", changed, test.changed}, {"removed", removed, test.removed}} {
+				if !reflect.DeepEqual(got.got, got.want) {
+					t.Errorf("%s %v, want %v", got.label, got.got, got.want)
+				}
+			}
+		})
+	}
+}
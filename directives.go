@@ -0,0 +1,153 @@
+package gocco
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"html"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ## Directives
+
+// A comment line starting with `gocco:` asks for something to be done to
+// the docs while they're built
+
+// `gocco:godoc fmt.Fprintf` puts the signature and documentation of a Go
+// symbol in place of the line, as `go doc` prints it, which is handy
+// when explaining how a source uses the standard library
+var godocPattern = regexp.MustCompile(`(?m)^[ \t]*gocco:godoc[ \t]+(\S+)[ \t]*$`)
+
+var (
+	godocCache     = make(map[string]string)
+	godocCacheLock sync.Mutex
+)
+
+// `gocco:exec go version` runs a command and puts its output in a code
+// block, so usage examples and help texts stay current. As the sources
+// shouldn't run whatever they like, only commands named in `-allow-exec`
+// are run, and nothing at all without it. Commands run from the working
+// directory, without a shell
+var execPattern = regexp.MustCompile(`(?m)^[ \t]*gocco:exec[ \t]+(.+?)[ \t]*$`)
+
+var (
+	allowedCommands = make(map[string]bool)
+	execCache       = make(map[string]string)
+	execCacheLock   sync.Mutex
+)
+
+// parse the comma separated `-allow-exec` list
+func setAllowedCommands(list string) {
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			allowedCommands[name] = true
+		}
+	}
+}
+
+// `expandDirectives` replaces the directives in the docs of every section
+func (g *Generator) expandDirectives(source string, sections *list.List) {
+	for e := sections.Front(); e != nil; e = e.Next() {
+		section := e.Value.(*Section)
+		// weights were read before any page, see `weight.go`
+		section.docsText = weightPattern.ReplaceAll(section.docsText, nil)
+		section.docsText = execPattern.ReplaceAllFunc(section.docsText, func(match []byte) []byte {
+			command := string(execPattern.FindSubmatch(match)[1])
+			output, err := runCommand(command)
+			if err != nil {
+				warn(source, section.line, "gocco:exec "+command+": "+err.Error())
+				return match
+			}
+			return []byte("\n" + fenced(output) + "\n")
+		})
+		section.docsText = godocPattern.ReplaceAllFunc(section.docsText, func(match []byte) []byte {
+			symbol := string(godocPattern.FindSubmatch(match)[1])
+			output, err := godoc(symbol)
+			if err != nil {
+				warn(source, 0, "gocco:godoc "+symbol+": "+err.Error())
+				return match
+			}
+			// an HTML block in markdown needs blank lines around it
+			return []byte("\n" + g.renderGodoc(output) + "\n")
+		})
+	}
+}
+
+// look up a symbol with `go doc`, as it prints it
+func godoc(symbol string) (string, error) {
+	godocCacheLock.Lock()
+	defer godocCacheLock.Unlock()
+	if output, ok := godocCache[symbol]; ok {
+		return output, nil
+	}
+	output, err := exec.Command("go", "doc", symbol).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s", bytes.TrimSpace(output))
+	}
+	godocCache[symbol] = string(output)
+	return string(output), nil
+}
+
+// `go doc` prints the package clause, the declaration and then the
+// documentation indented by four spaces. Whatever follows, like the
+// methods of a type, is left out
+func (g *Generator) renderGodoc(output string) string {
+	lines := strings.Split(output, "\n")
+	i := 0
+	if strings.HasPrefix(lines[0], "package ") {
+		i++
+	}
+	for i < len(lines) && lines[i] == "" {
+		i++
+	}
+	var signature, doc []string
+	for ; i < len(lines) && lines[i] != "" && !strings.HasPrefix(lines[i], "    "); i++ {
+		signature = append(signature, lines[i])
+	}
+	for ; i < len(lines) && (lines[i] == "" || strings.HasPrefix(lines[i], "    ")); i++ {
+		doc = append(doc, strings.TrimPrefix(lines[i], "    "))
+	}
+	rendered := g.markdown([]byte(strings.Join(doc, "\n")))
+	// blank lines would end the HTML block early
+	rendered = blankLines.ReplaceAll(rendered, []byte("\n"))
+	return fmt.Sprintf("<div class=\"godoc\"><pre><code>%s</code></pre>\n%s</div>",
+		html.EscapeString(strings.Join(signature, "\n")), bytes.TrimSpace(rendered))
+}
+
+var blankLines = regexp.MustCompile(`\n\s*\n`)
+
+// run an allowed command, returning what it printed
+func runCommand(command string) (string, error) {
+	args := strings.Fields(command)
+	if !allowedCommands[args[0]] {
+		return "", fmt.Errorf("%s isn't allowed, see -allow-exec", args[0])
+	}
+	execCacheLock.Lock()
+	defer execCacheLock.Unlock()
+	if output, ok := execCache[command]; ok {
+		return output, nil
+	}
+	output, err := exec.Command(args[0], args[1:]...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%v: %s", err, bytes.TrimSpace(output))
+	}
+	execCache[command] = string(output)
+	return string(output), nil
+}
+
+// put text in a fenced code block, with a fence longer than any run of
+// backticks in it
+var backticks = regexp.MustCompile("`+")
+
+func fenced(text string) string {
+	fence := "```"
+	for _, run := range backticks.FindAllString(text, -1) {
+		if len(run) >= len(fence) {
+			fence = run + "`"
+		}
+	}
+	return fence + "\n" + strings.TrimRight(text, "\n") + "\n" + fence
+}
@@ -0,0 +1,61 @@
+package gocco
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ## Drafts
+
+// A page still being written can say so in its front matter:
+//
+//     // ---
+//     // draft: true
+//     // ---
+//
+// It is generated at its usual address, so the link can be shared, but
+// left out of the jump menus, the index pages, the 404 page and the
+// search, and asks search engines not to index it. As the menus are
+// built before any page, the drafts are looked for up front
+var drafts = make(map[string]bool)
+
+// `findDrafts` reads the front matter of every source, quietly, as the
+// pages report problems with it later. The weights are picked up on the
+// way, see `weight.go`
+func findDrafts() {
+	for _, source := range sources {
+		sections := peek(source)
+		if sections == nil {
+			continue
+		}
+		meta := peekFrontMatter(sections)
+		if isTrue(meta["draft"]) {
+			drafts[source] = true
+		}
+		findWeight(source, sections, meta)
+	}
+}
+
+// whether a front matter value means yes
+func isTrue(value string) bool {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "yes" || value == "on" {
+		return true
+	}
+	yes, err := strconv.ParseBool(value)
+	return err == nil && yes
+}
+
+// the sources that are listed, leaving out drafts
+func listedSources(list []string) []string {
+	if len(drafts) == 0 {
+		return list
+	}
+	var listed []string
+	for _, source := range list {
+		if !drafts[source] {
+			listed = append(listed, source)
+		}
+	}
+	return listed
+}
@@ -0,0 +1,177 @@
+package gocco
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ## Editor previews
+
+// Editor plugins showing a literate preview next to the source would
+// have to start gocco on every keystroke. With `-editor-server` it stays
+// running instead, reading one JSON request per line from stdin and
+// answering each with one line on stdout:
+//
+//     {"id": 1, "method": "fragment", "file": "main.go", "text": "..."}
+//     {"id": 1, "html": "<div class=\"section\" ..."}
+//
+// The methods are
+//
+//   - `render`, the whole page of the file,
+//   - `fragment`, just its sections, each a `div.section` with its
+//     `div.docs` and `div.code`,
+//   - `selection`, the sections of the lines `start` to `end`, with
+//     the line numbers of the file,
+//   - `css`, the stylesheet the pages and fragments use.
+//
+// The text is the unsaved contents of the editor, the file is read when
+// it's left out. A request that fails is answered with an `error`, and
+// the server carries on until stdin is closed. Warnings still go to
+// stderr
+var editorServer bool
+
+// an `EditorRequest` is one line sent to the editor server
+type EditorRequest struct {
+	ID     interface{} `json:"id"`
+	Method string      `json:"method"`
+	File   string      `json:"file"`
+	Text   *string     `json:"text"`
+	Start  int         `json:"start"`
+	End    int         `json:"end"`
+}
+
+// an `EditorResponse` is the answer to a request with the same id
+type EditorResponse struct {
+	ID    interface{} `json:"id"`
+	HTML  string      `json:"html,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+// the longest request line, sources included
+const maxEditorRequest = 64 << 20
+
+// answer the requests on `in` until it ends
+func serveEditor(in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), maxEditorRequest)
+	encoder := json.NewEncoder(out)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var request EditorRequest
+		response := &EditorResponse{}
+		if err := json.Unmarshal(line, &request); err != nil {
+			response.Error = err.Error()
+		} else {
+			response.ID = request.ID
+			html, err := answerEditor(&request)
+			if err != nil {
+				response.Error = err.Error()
+			}
+			response.HTML = html
+		}
+		if err := encoder.Encode(response); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// render what a request asks for
+func answerEditor(request *EditorRequest) (string, error) {
+	switch request.Method {
+	case "css":
+		return Css, nil
+	case "render", "fragment", "selection":
+	default:
+		return "", fmt.Errorf("unknown method %q", request.Method)
+	}
+	if getLanguage(request.File) == nil {
+		return "", fmt.Errorf("%s: no language known", request.File)
+	}
+	var code []byte
+	if request.Text != nil {
+		code = []byte(*request.Text)
+	} else {
+		var err error
+		if code, err = readSource(request.File); err != nil {
+			return "", err
+		}
+	}
+	offset := 0
+	if request.Method == "selection" {
+		var err error
+		if code, offset, err = selectLines(code, request.Start, request.End); err != nil {
+			return "", err
+		}
+	}
+	type rendered struct {
+		html []byte
+		err  error
+	}
+	done := make(chan rendered, 1)
+	failure := isolate(request.File, func() {
+		sections, meta := command.prepareSections(request.File, code)
+		if request.Method == "render" {
+			_, html, err := command.renderPage(request.File, sections, meta)
+			done <- rendered{html, err}
+			return
+		}
+		html, err := renderFragment(sections, offset)
+		done <- rendered{html, err}
+	})
+	if failure != nil {
+		return "", failure
+	}
+	result := <-done
+	return string(result.html), result.err
+}
+
+// the lines `start` to `end` of `code`, counting from 1, and how many
+// lines come before them
+func selectLines(code []byte, start, end int) ([]byte, int, error) {
+	lines := bytes.SplitAfter(code, []byte("\n"))
+	if start < 1 || end < start || start > len(lines) {
+		return nil, 0, fmt.Errorf("no lines %d to %d", start, end)
+	}
+	if end > len(lines) {
+		end = len(lines)
+	}
+	return bytes.Join(lines[start-1:end], nil), start - 1, nil
+}
+
+// render sections without a page around them, numbering their lines
+// from `offset`
+func renderFragment(sections *list.List, offset int) ([]byte, error) {
+	anchorHeadings(sections)
+	ids := sectionIDs(sections)
+	var templateSections []*TemplateSection
+	for e, i := sections.Front(), 1; e != nil; e, i = e.Next(), i+1 {
+		section := e.Value.(*Section)
+		templateSections = append(templateSections, &TemplateSection{
+			DocsHTML:  string(section.DocsHTML),
+			CodeHTML:  string(section.CodeHTML),
+			Index:     i,
+			ID:        ids[i-1],
+			DocsText:  string(section.docsText),
+			CodeText:  string(section.codeText),
+			StartLine: section.line + offset,
+			EndLine:   section.end + offset,
+		})
+	}
+	return renderTemplate("fragment", FragmentHTML, templateSections)
+}
+
+// run the editor server on stdin and stdout
+func runEditorServer() {
+	if err := serveEditor(os.Stdin, os.Stdout); err != nil {
+		fail(exitInput, err)
+	}
+}
@@ -0,0 +1,52 @@
+package gocco
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ## Environment variables
+
+// Settings that differ between builds, like the address the docs go to
+// or the commit they were made from, usually come from the CI service's
+// environment. Values in the files given to `-book`, `-messages` and
+// `-analytics` may refer to variables as `${NAME}`; other uses of `$`
+// are left alone
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// `expandEnv` replaces the variables in a settings value. Unset variables
+// expand to nothing, with a warning naming the file they're used in
+func expandEnv(file, value string) string {
+	return envPattern.ReplaceAllStringFunc(value, func(match string) string {
+		name := envPattern.FindStringSubmatch(match)[1]
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			warn(file, 0, "environment variable "+name+" is not set")
+		}
+		return value
+	})
+}
+
+// Templates can read the environment with `{{ env "NAME" }}` too, but as
+// pages are published, only variables named in `-env` are handed out.
+// A deploy key can't end up on a page by accident
+var allowedEnv = make(map[string]bool)
+
+// parse the comma separated `-env` list
+func setAllowedEnv(list string) {
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			allowedEnv[name] = true
+		}
+	}
+}
+
+// the `env` template function
+func templateEnv(name string) (string, error) {
+	if !allowedEnv[name] {
+		return "", fmt.Errorf("environment variable %s is not allowed in templates, list it in -env", name)
+	}
+	return os.Getenv(name), nil
+}
@@ -0,0 +1,91 @@
+package gocco
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// ## Exit status
+
+// Scripts wrapping gocco can tell what went wrong from its exit status:
+//
+//   - `0` everything was documented
+//   - `1` checks found problems, like `gocco lint` findings or dangling
+//     references between chapters, or there were warnings with `-strict`
+//   - `2` the configuration is wrong: a bad flag, or a settings file
+//     that can't be read or understood
+//   - `3` a source couldn't be found or read
+//   - `4` a page couldn't be rendered or written
+//
+// When several things went wrong the highest status wins. A source that
+// fails doesn't stop the others, the failures are listed again once
+// everything else is done so they don't get lost among the progress
+const (
+	exitProblems = 1
+	exitConfig   = 2
+	exitInput    = 3
+	exitRender   = 4
+)
+
+// problems found along the way, which don't stop the other files from
+// being documented
+var inputErrors, renderFailures int32
+
+// what went wrong, to repeat at the end
+var (
+	failures     []string
+	failuresLock sync.Mutex
+)
+
+func recordFailure(v []interface{}) {
+	failuresLock.Lock()
+	defer failuresLock.Unlock()
+	failures = append(failures, fmt.Sprint(v...))
+}
+
+// log the reason and exit with `code` right away
+func fail(code int, v ...interface{}) {
+	log.Print(append([]interface{}{"gocco: "}, v...)...)
+	os.Exit(code)
+}
+
+func inputError(v ...interface{}) {
+	log.Print(append([]interface{}{"gocco: "}, v...)...)
+	recordFailure(v)
+	atomic.AddInt32(&inputErrors, 1)
+}
+
+func renderFailure(v ...interface{}) {
+	log.Print(append([]interface{}{"gocco: "}, v...)...)
+	recordFailure(v)
+	atomic.AddInt32(&renderFailures, 1)
+}
+
+// list the failures once more and exit with the status they call for
+func exit() {
+	failuresLock.Lock()
+	if len(failures) > 0 {
+		log.Print("gocco: ", len(failures), " failed:")
+		for _, failure := range failures {
+			log.Print("gocco:   ", failure)
+		}
+	}
+	failuresLock.Unlock()
+	os.Exit(exitStatus())
+}
+
+// the status to exit with once every file has been through
+func exitStatus() int {
+	switch {
+	case atomic.LoadInt32(&renderFailures) > 0:
+		return exitRender
+	case atomic.LoadInt32(&inputErrors) > 0:
+		return exitInput
+	case atomic.LoadInt32(&danglingReferences) > 0, strict && atomic.LoadInt32(&warnings) > 0:
+		return exitProblems
+	}
+	return 0
+}
@@ -0,0 +1,31 @@
+package gocco
+
+import "testing"
+
+func TestExitStatus(t *testing.T) {
+	defer func(input, render, dangling, warned int32, s bool) {
+		inputErrors, renderFailures, danglingReferences, warnings, strict = input, render, dangling, warned, s
+	}(inputErrors, renderFailures, danglingReferences, warnings, strict)
+	tests := []struct {
+		name                            string
+		input, render, dangling, warned int32
+		strict                          bool
+		want                            int
+	}{
+		{"clean", 0, 0, 0, 0, false, 0},
+		{"warnings", 0, 0, 0, 3, false, 0},
+		{"strict warnings", 0, 0, 0, 3, true, exitProblems},
+		{"dangling references", 0, 0, 2, 0, false, exitProblems},
+		{"input errors", 1, 0, 2, 3, true, exitInput},
+		{"render failures", 1, 1, 2, 3, true, exitRender},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inputErrors, renderFailures, danglingReferences, warnings, strict =
+				test.input, test.render, test.dangling, test.warned, test.strict
+			if got := exitStatus(); got != test.want {
+				t.Errorf("exit status %d, want %d", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package gocco
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/csv"
+	"encoding/json"
+	"log"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ## Exporting sections
+
+// `-export-sections file` writes where every section of the docs comes
+// from: its source, the lines it spans and the anchor on its page. Review
+// bots can use it to post the docs of the lines a pull request touches.
+// A file ending in `.csv` gets CSV, anything else JSON
+var exportFile string
+
+// an `ExportedSection` is one section in the export
+type ExportedSection struct {
+	Source string `json:"source"`
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+	Page   string `json:"page"`
+	Anchor string `json:"anchor"`
+	Docs   string `json:"docs"`
+}
+
+var (
+	exported     []*ExportedSection
+	exportedLock sync.Mutex
+)
+
+// remember the sections of the page written for `source`, before its
+// docs are rendered
+func recordSections(source string, sections *list.List) {
+	page := pagePath(source)
+	var records []*ExportedSection
+	ids := sectionIDs(sections)
+	for e, i := sections.Front(), 0; e != nil; e, i = e.Next(), i+1 {
+		section := e.Value.(*Section)
+		records = append(records, &ExportedSection{
+			Source: filepath.ToSlash(section.source),
+			Start:  section.line,
+			End:    section.end,
+			Page:   page,
+			Anchor: ids[i],
+			Docs:   strings.TrimSpace(string(section.docsText)),
+		})
+	}
+	exportedLock.Lock()
+	exported = append(exported, records...)
+	exportedLock.Unlock()
+}
+
+func writeSectionExport() {
+	exportedLock.Lock()
+	defer exportedLock.Unlock()
+	sort.SliceStable(exported, func(i, j int) bool {
+		if exported[i].Source != exported[j].Source {
+			return exported[i].Source < exported[j].Source
+		}
+		return exported[i].Start < exported[j].Start
+	})
+
+	out := new(bytes.Buffer)
+	if strings.HasSuffix(strings.ToLower(exportFile), ".csv") {
+		w := csv.NewWriter(out)
+		w.Write([]string{"source", "start", "end", "page", "anchor", "docs"})
+		for _, s := range exported {
+			w.Write([]string{s.Source, strconv.Itoa(s.Start), strconv.Itoa(s.End), s.Page, s.Anchor, s.Docs})
+		}
+		w.Flush()
+	} else {
+		data, err := json.MarshalIndent(map[string]interface{}{"sections": exported}, "", "  ")
+		if err != nil {
+			renderFailure(err)
+			return
+		}
+		out.Write(append(data, '\n'))
+	}
+	if err := writeFile(exportFile, out.Bytes()); err != nil {
+		renderFailure(err)
+		return
+	}
+	log.Println("gocco: sections -> ", exportFile)
+}
@@ -0,0 +1,111 @@
+package gocco
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// ## JSON output
+
+// Static site generators, search indexers and linters that want gocco's
+// reading of the sources shouldn't have to take its HTML apart. With
+// `-format json` every source gets `docs/<name>.json` where its page
+// would go, holding its sections with their docs and code, both as
+// written and as rendered:
+//
+//     {
+//       "source": "gocco.go",
+//       "title": "gocco.go",
+//       "sections": [
+//         {"index": 1, "id": "section-1", "start": 1, "end": 27,
+//          "docs": "**Gocco** is ...", "code": "package gocco\n",
+//          "docs_html": "<p><strong>Gocco</strong> ...", "code_html": "..."}
+//       ]
+//     }
+//
+// Besides the manifest, only what the rendered docs need is written:
+// copies of the images and videos they show. Social cards and the search
+// index are left out, they serve HTML pages. Links in the rendered docs
+// still lead to those pages
+var outputFormat = "html"
+
+// check the value of `-format`, for `pdf` see `pdf.go`
+func setFormat(value string) error {
+	switch value {
+	case "html", "json", "pdf":
+		outputFormat = value
+		return nil
+	}
+	return fmt.Errorf("unknown -format %q, use html, json or pdf", value)
+}
+
+// a `JSONPage` is what `-format json` writes for a source
+type JSONPage struct {
+	Source   string         `json:"source"`
+	Title    string         `json:"title"`
+	Sections []*JSONSection `json:"sections"`
+}
+
+// a `JSONSection` is a section of a `JSONPage`
+type JSONSection struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id"`
+	Anchor   string `json:"anchor,omitempty"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	Docs     string `json:"docs"`
+	Code     string `json:"code"`
+	DocsHTML string `json:"docs_html"`
+	CodeHTML string `json:"code_html"`
+}
+
+// where the JSON of a source goes, next to where its page would
+func jsonDestination(source string) string {
+	return strings.TrimSuffix(destination(source), ".html") + ".json"
+}
+
+// `writeJSONPage` encodes the sections of a source as JSON, rendered the
+// way its page would show them, returning what writes them
+func (g *Generator) writeJSONPage(source string, sections *list.List, meta FrontMatter) (write func()) {
+	_, data := g.pageData(source, sections, meta)
+	page := &JSONPage{Source: data.Path, Title: data.Title}
+	for _, s := range data.Sections {
+		page.Sections = append(page.Sections, &JSONSection{s.Index, s.ID, s.Anchor, s.StartLine, s.EndLine,
+			s.DocsText, s.CodeText, s.DocsHTML, s.CodeHTML})
+	}
+	// the HTML is kept readable rather than escaped for a script tag
+	out := new(bytes.Buffer)
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(page); err != nil {
+		return func() { renderFailure(source, ": ", err) }
+	}
+	return func() {
+		if exportFile != "" {
+			recordSections(source, sections)
+		}
+		dest := jsonDestination(source)
+		if err := writeOutput(dest, source, out.Bytes()); err != nil {
+			renderFailure(err)
+			return
+		}
+		log.Println("gocco: ", source, " -> ", dest)
+	}
+}
+
+// document every source as JSON, see `writeJSONPage`
+func generateJSON() {
+	if joinName != "" {
+		generateJoined()
+	} else {
+		forEachSource(func(i int, source string) {
+			command.generateDocumentation(source)
+		})
+	}
+	writeManifest()
+}
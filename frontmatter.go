@@ -0,0 +1,73 @@
+package gocco
+
+import (
+	"bytes"
+	"container/list"
+	"regexp"
+	"strings"
+)
+
+// ## Front matter
+
+// A file can change how its own page is rendered with a block of
+// `key: value` lines fenced by `---` at the very start of its first
+// comment, which is removed from the docs:
+//
+//     // ---
+//     // style: monokai
+//     // layout: linear
+//     // ---
+type FrontMatter map[string]string
+
+// the keys a front matter block may set
+var frontMatterKeys = map[string]bool{
+	// the Pygments style used to colour this page's code
+	"style": true,
+	// the layout of the page, see `-layout`
+	"layout": true,
+	// the title and description of the page
+	"title":       true,
+	"description": true,
+	// whether the page is a draft, left out of menus and search
+	"draft": true,
+	// where the page goes among the others
+	"weight": true,
+	// how many levels the headings of the docs are demoted
+	"heading_offset": true,
+}
+
+var frontMatterPattern = regexp.MustCompile(`(?s)\A\s*---\n(.*?\n)?---\n`)
+
+// `extractFrontMatter` strips the front matter from the docs of the first
+// section of `source` and returns its settings
+func extractFrontMatter(source string, sections *list.List) FrontMatter {
+	meta := make(FrontMatter)
+	if sections.Len() == 0 {
+		return meta
+	}
+	first := sections.Front().Value.(*Section)
+	match := frontMatterPattern.FindSubmatchIndex(first.docsText)
+	if match == nil {
+		return meta
+	}
+	var block []byte
+	if match[2] >= 0 {
+		block = first.docsText[match[2]:match[3]]
+	}
+	// the docs now start further down the source
+	first.line += bytes.Count(first.docsText[:match[1]], []byte("\n"))
+	first.docsText = first.docsText[match[1]:]
+	for _, line := range bytes.Split(block, []byte("\n")) {
+		parts := strings.SplitN(string(line), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(parts[0]))
+		if !frontMatterKeys[key] {
+			warn(source, 0, "unknown front matter key "+key)
+			continue
+		}
+		meta[key] = strings.TrimSpace(parts[1])
+	}
+	return meta
+}
@@ -8,7 +8,7 @@
 //
 // If you install Gocco, you can run it from the command-line:
 //
-// gocco *.go
+// gocco \*.go
 //
 // ...will generate an HTML documentation page for each of the named source
 // files, with a menu linking to the other pages, saving it into a `docs`
@@ -20,14 +20,19 @@
 // To install Gocco, first make sure you have [Pygments](http://pygments.org/)
 // Then, with the go tool:
 //
-//     go get github.com/nikhilm/gocco
-package main
+//     go get github.com/nikhilm/gocco/cmd/gocco
+//
+// The command is a thin wrapper around this package, which other tools
+// can import to document sources themselves, see `Generate`.
+package gocco
 
 import (
+	"bufio"
 	"bytes"
 	"container/list"
+	"crypto/sha256"
 	"flag"
-	"github.com/russross/blackfriday"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
@@ -36,9 +41,11 @@ import (
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
-	"sync"
+	"sync/atomic"
 	"text/template"
+	"time"
 )
 
 // ## Types
@@ -53,6 +60,14 @@ type Section struct {
 	codeText []byte
 	DocsHTML []byte
 	CodeHTML []byte
+	// the file the section comes from, and the lines it starts and
+	// ends on
+	source string
+	line   int
+	end    int
+	// a digest of the docs and code as they were written, see
+	// `sectionIDs`
+	hash string
 }
 
 // a `TemplateSection` is a section that can be passed
@@ -60,16 +75,48 @@ type Section struct {
 type TemplateSection struct {
 	DocsHTML string
 	CodeHTML string
-	// The `Index` field is used to create anchors to sections
+	// The `Index` field is the number of the section on its page, and
+	// `ID` its anchor, which depends on `-section-ids`
 	Index int
+	ID    string
+	// Long code blocks start out folded, see `-collapse`
+	Collapsed bool
+	// On a page joining several files, the file the section starts,
+	// and the anchor of its heading
+	File       string
+	FileAnchor string
+	// Repeats of the summary along tall code, see `-balance`
+	Recaps []*Recap
+	// The Markdown of the comments and the plain code, as they were
+	// before rendering, and the lines of the source the section spans,
+	// for templates that want to do their own thing with them
+	DocsText  string
+	CodeText  string
+	StartLine int
+	EndLine   int
+	// The named anchor of the section, and whether it is the id of the
+	// heading it opens with, see `anchors.go`
+	Anchor        string
+	AnchorHeading bool
+}
+
+// a `JumpLink` is an entry of the "Jump To" menu
+type JumpLink struct {
+	Name string
+	Href string
+	// In book mode, the title of the part this link starts
+	Part string
 }
 
 // a `Language` describes a programming language
 type Language struct {
 	// the `Pygments` name of the language
 	name string
-	// The comment delimiter
-	symbol string
+	// The comment delimiter, and those of block comments if it has
+	// them, see `blockComments`
+	symbol     string
+	blockStart string
+	blockEnd   string
 	// The regular expression to match the comment delimiter
 	commentMatcher *regexp.Regexp
 	// Used as a placeholder so we can parse back Pygments output
@@ -81,27 +128,148 @@ type Language struct {
 
 // a `TemplateData` is per-file
 type TemplateData struct {
-	// Title of the HTML output
+	// Title of the HTML output, the name of the file unless its front
+	// matter gives one
 	Title string
+	// Name of the whole site and the description of the page, see
+	// `-title` and `-description`
+	SiteTitle   string
+	SiteName    string
+	Description string
+	// Path of the source file, shown in the page header
+	Path string
+	// Writing direction of the docs, `ltr` or `rtl`
+	Direction string
+	// The address the page is published at, with `-base-url`, and
+	// that of its social card
+	Canonical  string
+	SocialCard string
+	// Markup for the head of the page, see `-analytics`
+	Analytics string
+	// Show a search box, and where the OpenSearch document is
+	Search     bool
+	OpenSearch string
+	// Link to the index page, when one is generated
+	Index string
+	// Link to the copy of the source next to the page, see `-raw`
+	Raw string
+	// The way from the page back to `docs/`, for the files shared by
+	// every page
+	Root string
 	// The Sections making up this file
 	Sections []*TemplateSection
 	// A full list of source files so that a table-of-contents can
-	// be generated
+	// be generated, and the links to their docs
 	Sources []string
+	Jump    []*JumpLink
 	// Only generate the TOC is there is more than one file
 	// Go's templating system does not allow expressions in the
 	// template, so calculate it outside
 	Multiple bool
+	// Load scripts from `gocco.js` instead of inlining them
+	CSP bool
+	// Keep each section's docs in view while its code scrolls past
+	StickyDocs bool
+	// How docs and code are laid out, `parallel` or `linear`
+	Layout string
+	// The files of a single page, listed at its top
+	Files []*JumpLink
+	// Keep search engines away from draft pages
+	Draft bool
+	// Renamed anchors of the page and their new names, as JSON
+	Redirects string
+	// When the docs were generated, zero unless `-footer` is given,
+	// and the footer line telling so
+	Generated   time.Time
+	GeneratedAt string
+	// The pages before and after this one, for keyboard navigation
+	Prev string
+	Next string
+	// The Pygments style of the page when it isn't the default, and
+	// its stylesheet
+	Style      string
+	StyleSheet string
+	// The whole stylesheet, with `-single-file`, to be put into the
+	// page instead of linking `gocco.css`
+	InlineCSS string
+	// The addresses of highlight.js and its theme, with `-highlighter
+	// client`
+	HighlightScript string
+	HighlightStyle  string
 }
 
 // a map of all the languages we know
 var languages map[string]*Language
 
-// paths of all the source files, sorted
+// paths of all the source files, sorted. The pages are rendered from
+// `command.Sources`, see `commandSettings`
 var sources []string
 
-// absolute path to get resources
-var packageLocation string
+// code blocks with more lines than this start out collapsed, zero keeps
+// every block expanded
+var collapseLines int
+
+// pin the explanation of a section to the top of the screen while its
+// code is scrolled, set with `-sticky-docs`
+var stickyDocs bool
+
+// `parallel` pages put the docs beside their code, `linear` ones put
+// them above it in a single column, like docco's linear layout, which
+// reads better on narrow screens. Set with `-layout`
+var layout = "parallel"
+
+// check the value of `-layout`
+func setLayout(value string) error {
+	if checkLayout(value) != nil {
+		return fmt.Errorf("unknown -layout %q, use parallel or linear", value)
+	}
+	layout = value
+	return nil
+}
+
+// check a layout, of `-layout` or of a page's front matter
+func checkLayout(value string) error {
+	switch value {
+	case "parallel", "linear":
+		return nil
+	}
+	return fmt.Errorf("unknown layout %q, use parallel or linear", value)
+}
+
+// whether pages must work under a Content-Security-Policy that forbids
+// inline scripts, set with `-csp`
+var csp bool
+
+// the directory the docs go into, `docs` unless `-output` says otherwise.
+// Pages link to each other and to the shared files relatively, so it can
+// be anywhere, like `public/` or an absolute path
+var outputDir = "docs"
+
+// permissions for generated files and directories, configurable with
+// `-file-mode` and `-dir-mode` for people deploying to shared hosts
+var fileMode os.FileMode = 0644
+var dirMode os.FileMode = 0755
+
+// a `modeFlag` lets an octal permission be passed on the command line
+type modeFlag struct {
+	mode *os.FileMode
+}
+
+func (m modeFlag) String() string {
+	if m.mode == nil {
+		return ""
+	}
+	return fmt.Sprintf("%#o", uint32(*m.mode))
+}
+
+func (m modeFlag) Set(value string) error {
+	perm, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || perm&^uint64(os.ModePerm) != 0 {
+		return fmt.Errorf("invalid permission %q", value)
+	}
+	*m.mode = os.FileMode(perm)
+	return nil
+}
 
 // Wrap the code in these
 const highlightStart = "<div class=\"highlight\"><pre>"
@@ -112,22 +280,78 @@ const highlightEnd = "</pre></div>"
 // Generate the documentation for a single source file
 // by splitting it into sections, highlighting each section
 // and putting it together.
-// The WaitGroup is used to signal we are done, so that the main
-// goroutine waits for all the sub goroutines
-func generateDocumentation(source string, wg *sync.WaitGroup) {
-	code, err := ioutil.ReadFile(source)
+// It runs on one of the workers of `forEachSource`
+func (g *Generator) generateDocumentation(source string) {
+	// work given up on by `isolate` mustn't write anything any more, so
+	// it hands back what is left to do rather than doing it
+	finish := make(chan func(), 1)
+	err := isolate(source, func() {
+		if isPackageDoc(source) {
+			finish <- g.generateOverview(source)
+			return
+		}
+		sections, meta, err := g.loadSections(source)
+		if err != nil {
+			finish <- func() { inputError(err) }
+			return
+		}
+		finish <- g.generateHTML(source, sections, meta)
+	})
 	if err != nil {
-		log.Panic(err)
+		renderFailure(err)
+		return
 	}
-	sections := parse(source, code)
-	highlight(source, sections)
-	generateHTML(source, sections)
-	wg.Done()
+	(<-finish)()
+}
+
+// read, parse and highlight a source
+func (g *Generator) loadSections(source string) (*list.List, FrontMatter, error) {
+	code, err := readSource(source)
+	if err != nil {
+		return nil, nil, err
+	}
+	sections, meta := g.prepareSections(source, code)
+	return sections, meta, nil
+}
+
+// parse and highlight the contents of a source
+func (g *Generator) prepareSections(source string, code []byte) (*list.List, FrontMatter) {
+	return g.prepareStages(source, code, func(stage string, work func()) { work() })
+}
+
+// `prepareStages` is `prepareSections`, handing each stage to `run`,
+// which `gocco bench` times them with
+func (g *Generator) prepareStages(source string, code []byte, run func(stage string, work func())) (*list.List, FrontMatter) {
+	var sections *list.List
+	var meta FrontMatter
+	run("parse", func() {
+		if checkProse && g.cli {
+			for _, problem := range lintProse(source, docLines(source, code)) {
+				warn(problem.Source, problem.Line, problem.Message)
+			}
+		}
+		sections = parse(source, code)
+		meta = extractFrontMatter(source, sections)
+		if tidyWhitespace {
+			tidyCode(source, sections)
+		}
+		if (heatmap || stats) && g.cli {
+			recordDensity(source, sections)
+		}
+		checkMarkdown(sections)
+	})
+	run("directives", func() { g.expandDirectives(source, sections) })
+	run("highlight", func() {
+		g.highlight(source, sections)
+		g.linkPackages(source, code, sections)
+		g.formatCode(sections)
+	})
+	run("markdown", func() { g.renderMarkdown(sections, g.headingOffset(source, meta)) })
+	return sections, meta
 }
 
 // Parse splits code into `Section`s
 func parse(source string, code []byte) *list.List {
-	lines := bytes.Split(code, []byte("\n"))
 	sections := new(list.List)
 	sections.Init()
 	language := getLanguage(source)
@@ -137,48 +361,64 @@ func parse(source string, code []byte) *list.List {
 	var docsText = new(bytes.Buffer)
 
 	// save a new section
-	save := func(docs, code []byte) {
+	start := 1
+	save := func(docs, code []byte, end int) {
 		// deep copy the slices since slices always refer to the same storage
 		// by default
 		docsCopy, codeCopy := make([]byte, len(docs)), make([]byte, len(code))
 		copy(docsCopy, docs)
 		copy(codeCopy, code)
-		sections.PushBack(&Section{docsCopy, codeCopy, nil, nil})
+		if end < start {
+			end = start
+		}
+		sections.PushBack(&Section{docsCopy, codeCopy, nil, nil, source, start, end, contentHash(docsCopy, codeCopy)})
 	}
 
-	for _, line := range lines {
+	lines := splitLines(language, code)
+	for i, line := range lines {
 		// if the line is a comment
-		if language.commentMatcher.Match(line) {
+		if line.docs {
 			// but there was previous code
 			if hasCode {
 				// we need to save the existing documentation and text
 				// as a section and start a new section since code blocks
 				// have to be delimited before being sent to Pygments
-				save(docsText.Bytes(), codeText.Bytes())
+				save(docsText.Bytes(), codeText.Bytes(), i)
+				start = i + 1
 				hasCode = false
 				codeText.Reset()
 				docsText.Reset()
 			}
-			docsText.Write(language.commentMatcher.ReplaceAll(line, nil))
+			docsText.Write(line.text)
 			docsText.WriteString("\n")
 		} else {
 			hasCode = true
-			codeText.Write(line)
+			codeText.Write(line.text)
 			codeText.WriteString("\n")
 		}
 	}
-	// save any remaining parts of the source file
-	save(docsText.Bytes(), codeText.Bytes())
+	// save any remaining parts of the source file, which ends on the
+	// last line that isn't just the final newline
+	last := len(lines)
+	if bytes.HasSuffix(code, []byte("\n")) {
+		last--
+	}
+	save(docsText.Bytes(), codeText.Bytes(), last)
 	return sections
 }
 
 // `highlight` pipes the source to Pygments, section by section
 // delimited by dividerText, then reads back the highlighted output,
 // searches for the delimiters and extracts the HTML version of the code
-// and documentation for each `Section`
-func highlight(source string, sections *list.List) {
+// and documentation for each `Section`. With `-highlighter client` the
+// code is left to the browser instead
+func (g *Generator) highlight(source string, sections *list.List) {
+	if clientHighlighting() {
+		g.highlightClient(source, sections)
+		return
+	}
 	language := getLanguage(source)
-	pygments := exec.Command("pygmentize", "-l", language.name, "-f", "html", "-O", "encoding=utf-8")
+	pygments := exec.Command("pygmentize", "-l", language.name, "-f", "html", "-O", g.pygmentsOptions())
 	pygmentsInput, _ := pygments.StdinPipe()
 	pygmentsOutput, _ := pygments.StdoutPipe()
 	// start the process before we start piping data to it
@@ -208,102 +448,719 @@ func highlight(source string, sections *list.List) {
 		fragment := output[0:index[0]]
 		output = output[index[1]:]
 		e.Value.(*Section).CodeHTML = bytes.Join([][]byte{[]byte(highlightStart), []byte(highlightEnd)}, fragment)
-		e.Value.(*Section).DocsHTML = blackfriday.MarkdownCommon(e.Value.(*Section).docsText)
 	}
 }
 
-// compute the output location (in `docs/`) for the file
+// render the docs of every section from markdown, with the headings
+// demoted by `offset` levels
+func (g *Generator) renderMarkdown(sections *list.List, offset int) {
+	for e := sections.Front(); e != nil; e = e.Next() {
+		e.Value.(*Section).DocsHTML = g.markdownOffset(e.Value.(*Section).docsText, offset)
+	}
+}
+
+// compute the output location (in `docs/`) for the file.
+// Only the final extension is replaced, files without one simply get
+// `.html` appended and dotfiles such as `.bashrc` keep their full name.
+// An `-out-name` template decides instead when given, unless it fails
+// on the source
 func destination(source string) string {
-	base := filepath.Base(source)
-	return "docs/" + base[0:strings.LastIndex(base, filepath.Ext(base))] + ".html"
+	if singleFile != "" && source == joinName {
+		return singleFile
+	}
+	if outName != nil {
+		if dest, err := namedDestination(source); err == nil {
+			return dest
+		}
+	}
+	base := filepath.Base(filepath.Clean(source))
+	name := strings.TrimSuffix(base, filepath.Ext(base))
+	if name == "" {
+		name = base
+	}
+	return filepath.Join(outputDir, pageDirOf(filepath.Dir(source)), name+".html")
 }
 
-// render the final HTML
-func generateHTML(source string, sections *list.List) {
+// render the final HTML, returning what writes it to `docs/`
+func (g *Generator) generateHTML(source string, sections *list.List, meta FrontMatter) (write func()) {
+	if outputFormat == "json" {
+		return g.writeJSONPage(source, sections, meta)
+	}
+	var store func() bool
+	// the page is only kept in memory when something still has to
+	// look at it, or when the file may be given up on before it's
+	// written, otherwise it goes straight into its file
+	if validate || holdingPages() || fileTimeout > 0 {
+		dest, html, err := g.renderPage(source, sections, meta)
+		store = func() bool {
+			if err != nil {
+				renderFailure(source, ": ", err)
+				return false
+			}
+			log.Println("gocco: ", source, " -> ", dest)
+			if holdingPages() {
+				holdPage(dest, source, html)
+			} else if err := writeOutput(dest, source, html); err != nil {
+				renderFailure(err)
+				return false
+			}
+			return true
+		}
+	} else {
+		dest, data := g.pageData(source, sections, meta)
+		store = func() bool {
+			err := writeStream(dest, source, func(w io.Writer) error {
+				return executeTemplate(w, "gocco", HTML, data)
+			})
+			if err != nil {
+				renderFailure(source, ": ", err)
+				return false
+			}
+			log.Println("gocco: ", source, " -> ", dest)
+			return true
+		}
+	}
+	return func() {
+		if exportFile != "" {
+			recordSections(source, sections)
+		}
+		if !store() {
+			return
+		}
+		if outputFormat == "pdf" {
+			addPDFPage(destination(source), source)
+		}
+		if err := writeRaw(source); err != nil {
+			renderFailure(err)
+		}
+	}
+}
+
+// render the page of a source, returning where it belongs
+func (g *Generator) renderPage(source string, sections *list.List, meta FrontMatter) (string, []byte, error) {
+	dest, data := g.pageData(source, sections, meta)
+	html, err := goccoTemplate(data)
+	if err != nil {
+		return dest, nil, err
+	}
+	if validate && g.cli {
+		validateSections(sections, html)
+	}
+	return dest, html, nil
+}
+
+// gather what the page of a source shows, and where it belongs
+func (g *Generator) pageData(source string, sections *list.List, meta FrontMatter) (string, TemplateData) {
 	title := filepath.Base(source)
+	if singlePage {
+		title = siteName()
+	}
+	if meta["title"] != "" {
+		title = meta["title"]
+	}
+	description := siteDescription
+	if meta["description"] != "" {
+		description = meta["description"]
+	}
 	dest := destination(source)
+	style, styleSheet := meta["style"], ""
+	if style == "" {
+		style = g.Style
+	}
+	if style != "" && !clientHighlighting() {
+		css, err := styleCSS(style)
+		if err != nil {
+			log.Println("gocco: ", source, ": ", err)
+			style = ""
+		}
+		styleSheet = css
+	}
+	if clientHighlighting() {
+		style = ""
+	}
+	pageLayout := g.Layout
+	if value := meta["layout"]; value != "" {
+		if err := checkLayout(value); err != nil {
+			warn(source, 0, err.Error())
+		} else {
+			pageLayout = value
+		}
+	}
+	headings := anchorHeadings(sections)
+	insertTOC(sections, headings)
+	names := nameSections(sections, headings)
+	ids := sectionIDs(sections)
+	g.resolveReferences(source, sections, headings)
+	g.rewriteSourceLinks(source, sections)
+	if g.cli {
+		embedImages(source, sections)
+	}
+	embedMedia(source, sections, g.cli)
+	if search && g.cli && outputFormat != "json" && !isTrue(meta["draft"]) {
+		indexForSearch(source, title, sections)
+	}
 	// convert every `Section` into corresponding `TemplateSection`
 	sectionsArray := make([]*TemplateSection, sections.Len())
+	previous := ""
 	for e, i := sections.Front(), 0; e != nil; e, i = e.Next(), i+1 {
 		var sec = e.Value.(*Section)
 		docsBuf := bytes.NewBuffer(sec.DocsHTML)
 		codeBuf := bytes.NewBuffer(sec.CodeHTML)
-		sectionsArray[i] = &TemplateSection{docsBuf.String(), codeBuf.String(), i + 1}
+		lines := bytes.Count(bytes.TrimRight(sec.codeText, "\n"), []byte("\n")) + 1
+		collapsed := collapseLines > 0 && lines > collapseLines
+		sectionsArray[i] = &TemplateSection{docsBuf.String(), codeBuf.String(), i + 1, ids[i], collapsed, "", "", nil,
+			string(sec.docsText), string(sec.codeText), sec.line, sec.end,
+			names[i].id, names[i].heading}
+		if !collapsed {
+			sectionsArray[i].Recaps = g.recaps(sec, lines)
+		}
+		// joined pages get a heading wherever the next file starts
+		if joinName != "" && sec.source != previous {
+			sectionsArray[i].File = filepath.ToSlash(sec.source)
+			sectionsArray[i].FileAnchor = fileAnchor(sec.source)
+		}
+		previous = sec.source
+	}
+	jump := g.jumpLinks(source)
+	redirects := ""
+	if g.cli {
+		redirects = redirectsJSON(anchorRedirects(pagePath(source), names))
+	}
+	card := ""
+	if socialCards && baseURL != "" && g.cli && outputFormat != "json" {
+		card = writeCard(source, title)
 	}
 	// run through the Go template
-	html := goccoTemplate(TemplateData{title, sectionsArray, sources, len(sources) > 1})
-	log.Println("gocco: ", source, " -> ", dest)
-	ioutil.WriteFile(dest, html, 0644)
+	prev, next := g.neighbours(source)
+	root := rootOf(dest)
+	highlightScript, highlightStyle := g.highlightAssets(root)
+	return dest, TemplateData{
+		Title:           title,
+		SiteTitle:       siteTitle,
+		SiteName:        siteName(),
+		Description:     description,
+		Path:            filepath.ToSlash(source),
+		Index:           indexPage(),
+		Raw:             rawLink(source),
+		Direction:       pageDirection(sections),
+		Canonical:       siteURL(pagePath(source)),
+		SocialCard:      card,
+		Root:            root,
+		Analytics:       analytics,
+		Search:          search && g.cli,
+		OpenSearch:      openSearchURL(),
+		Sections:        sectionsArray,
+		Sources:         g.Sources,
+		Jump:            jump,
+		Multiple:        len(g.Sources) > 1,
+		CSP:             csp,
+		StickyDocs:      stickyDocs,
+		Layout:          pageLayout,
+		Files:           g.fileContents(),
+		Draft:           isTrue(meta["draft"]),
+		Redirects:       redirects,
+		Prev:            prev,
+		Next:            next,
+		Generated:       generatedAt,
+		GeneratedAt:     generatedText(),
+		Style:           style,
+		StyleSheet:      styleSheet,
+		InlineCSS:       inlineCSS(),
+		HighlightScript: highlightScript,
+		HighlightStyle:  highlightStyle,
+	}
+}
+
+// find the pages documenting the sources around `source`, empty at
+// either end of the list. Drafts are skipped, and have no neighbours
+func (g *Generator) neighbours(source string) (prev, next string) {
+	listed := listedSources(g.Sources)
+	for i, s := range listed {
+		if s != source {
+			continue
+		}
+		if i > 0 {
+			prev = pageLink(source, listed[i-1])
+		}
+		if i < len(listed)-1 {
+			next = pageLink(source, listed[i+1])
+		}
+	}
+	return
+}
+
+func goccoTemplate(data TemplateData) ([]byte, error) {
+	return renderTemplate("gocco", HTML, data)
+}
+
+// introduce the functions that the templates need
+var templateFuncs = template.FuncMap{
+	"base":        filepath.Base,
+	"destination": destination,
+	"js":          func() string { return Js },
+	"searchJs":    func() string { return SearchJs },
+	"statsJs":     func() string { return StatsJs },
+	"t":           message,
+	"lang":        func() string { return uiLanguage },
+	"messages":    messagesJSON,
+	"styleFile":   styleFile,
+	"asset":       assetName,
+	"env":         templateEnv,
+	"page":        pagePath,
 }
 
-func goccoTemplate(data TemplateData) []byte {
+// run `data` through one of the templates in `resources.go`
+func renderTemplate(name, text string, data interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := executeTemplate(buf, name, text, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// `executeTemplate` renders a template into `w` as it goes, see
+// `renderTemplate`
+func executeTemplate(w io.Writer, name, text string, data interface{}) error {
 	// this hack is required because `ParseFiles` doesn't
 	// seem to work properly, always complaining about empty templates
-	t, err := template.New("gocco").Funcs(
-		// introduce the two functions that the template needs
-		template.FuncMap{
-			"base":        filepath.Base,
-			"destination": destination,
-		}).Parse(HTML)
-	if err != nil {
-		panic(err)
+	// the project may bring its own version of the template
+	if override, ok := templateOverrides[name]; ok {
+		text = override
 	}
-	buf := new(bytes.Buffer)
-	err = t.Execute(buf, data)
+	t, err := template.New(name).Funcs(templateFuncs).Parse(text)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	return buf.Bytes()
+	return t.Execute(w, data)
 }
 
 // get a `Language` given a path
 func getLanguage(source string) *Language {
-	return languages[filepath.Ext(source)]
+	return languages[strings.ToLower(filepath.Ext(source))]
 }
 
 // make sure `docs/` exists
-func ensureDirectory(name string) {
-	os.MkdirAll(name, 0755)
+func ensureDirectory(name string) error {
+	// the directories about to be made, from the deepest up. Those that
+	// are already there keep their permissions
+	var missing []string
+	for dir := filepath.Clean(name); ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		missing = append(missing, dir)
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	if err := os.MkdirAll(name, dirMode); err != nil {
+		return err
+	}
+	// the umask may have masked off bits, apply the mode explicitly
+	for _, dir := range missing {
+		if err := os.Chmod(dir, dirMode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write an output file with the configured permissions. `WriteFile` only
+// applies the mode (minus the umask) when creating, so set it afterwards
+func writeFile(name string, data []byte) error {
+	return writeOutput(name, "", data)
+}
+
+// write an output file made from `source`, listing it in the manifest
+func writeOutput(name, source string, data []byte) error {
+	if err := ensureDirectory(filepath.Dir(name)); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(name, data, fileMode); err != nil {
+		return err
+	}
+	addToManifest(name, source, data)
+	return os.Chmod(name, fileMode)
+}
+
+// `writeStream` is `writeOutput` for a file that `write` produces bit by
+// bit, which is written as it comes rather than held in memory whole. It
+// goes into a temporary file next to `name` that replaces it once
+// complete, so a failure halfway leaves the previous file in place
+func writeStream(name, source string, write func(w io.Writer) error) error {
+	if err := ensureDirectory(filepath.Dir(name)); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	hash := sha256.New()
+	counter := &countingWriter{}
+	out := bufio.NewWriter(f)
+	err = write(io.MultiWriter(out, hash, counter))
+	if err == nil {
+		err = out.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		// temporary files are only readable by their owner
+		err = os.Chmod(f.Name(), fileMode)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), name)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	recordManifest(name, source, hash.Sum(nil), counter.n)
+	return nil
+}
+
+// a `countingWriter` counts the bytes written through it
+type countingWriter struct {
+	n int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += len(p)
+	return len(p), nil
 }
 
 func setupLanguages() {
 	languages = make(map[string]*Language)
-	// you should add more languages here
-	// only the first two fields should change, the rest should
-	// be `nil, "", nil`
-	languages[".go"] = &Language{"go", "//", nil, "", nil}
+	// more languages go into `builtinLanguages`, the rest of the fields
+	// is filled in by `setup`
+	for ext, language := range builtinLanguages {
+		block := blockComments[language[0]]
+		languages[ext] = &Language{language[0], language[1], block[0], block[1], nil, "", nil}
+	}
+}
+
+// create the regular expressions based on the language comment symbol
+func prepareLanguage(lang *Language) {
+	// languages with only block comments, like HTML, are divided
+	// with one of those
+	if lang.symbol == "" {
+		lang.dividerText, lang.dividerHTML = blockDivider(lang)
+		return
+	}
+	symbol := regexp.QuoteMeta(lang.symbol)
+	// Lisps and Erlang double `;` and `%` by convention, `#` stays
+	// single so that markdown headings survive
+	if len(lang.symbol) == 1 && lang.symbol != "#" {
+		symbol += "+"
+	}
+	lang.commentMatcher, _ = regexp.Compile("^\\s*" + symbol + regexp.QuoteMeta(docMarker) + "\\s?")
+	lang.dividerText = "\n" + lang.symbol + "DIVIDER\n"
+	lang.dividerHTML, _ = regexp.Compile("\\n*<span class=\"c1?\">" + symbol + "DIVIDER<\\/span>\\n*")
 }
 
 func setup() {
 	setupLanguages()
-
-	// create the regular expressions based on the language comment symbol
 	for _, lang := range languages {
-		lang.commentMatcher, _ = regexp.Compile("^\\s*" + lang.symbol + "\\s?")
-		lang.dividerText = "\n" + lang.symbol + "DIVIDER\n"
-		lang.dividerHTML, _ = regexp.Compile("\\n*<span class=\"c1?\">" + lang.symbol + "DIVIDER<\\/span>\\n*")
+		prepareLanguage(lang)
 	}
 }
 
-// let's Go!
-func main() {
-	setup()
+// let's Go! `Main` runs the gocco command, with the arguments in
+// `os.Args`
+func Main() {
+	setupOnce.Do(setup)
 
-	flag.Parse()
-	sources = flag.Args()
-	sort.Strings(sources)
+	// subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "styles":
+			stylesCommand(os.Args[2:])
+			return
+		case "lint":
+			lintCommand(os.Args[2:])
+			return
+		case "verify-theme":
+			verifyThemeCommand(os.Args[2:])
+			return
+		case "bench":
+			benchCommand(os.Args[2:])
+			return
+		case "diff-output":
+			diffOutputCommand(os.Args[2:])
+			return
+		case "audit":
+			auditCommand(os.Args[2:])
+			return
+		case "serve":
+			serveCommand(os.Args[2:])
+			return
+		case "toc":
+			tocCommand(os.Args[2:])
+			return
+		}
+	}
 
-	if flag.NArg() <= 0 {
+	flag.Var(modeFlag{&fileMode}, "file-mode", "permissions of generated files")
+	flag.Var(modeFlag{&dirMode}, "dir-mode", "permissions of generated directories")
+	flag.Var(&symlinks, "symlinks", "how to treat symlinked sources: follow or skip")
+	flag.IntVar(&collapseLines, "collapse", 0, "collapse code blocks longer than this many lines")
+	flag.IntVar(&balanceLines, "balance", 0, "repeat the summary of a section's docs every this many lines of its code")
+	flag.BoolVar(&checkLinks, "check-links", checkLinks, "with -book or -join, check the links between pages and write none when one is broken")
+	flag.BoolVar(&watch, "watch", false, "keep running after the build, rendering the page of a source again whenever it is saved")
+	highlighterName := flag.String("highlighter", highlighter, "what highlights the code: pygments, while building, or client, highlight.js in the browser")
+	flag.StringVar(&highlightJS, "highlight-js", highlightJS, "address or local directory of the highlight.js release used by -highlighter client")
+	format := flag.String("format", outputFormat, "what to write for each source: html pages, json with the sections for other tools, or pdf printed from the pages")
+	flag.StringVar(&chromePath, "chrome", "", "the Chrome or Chromium printing the pages for -format pdf")
+	idMode := flag.String("section-ids", sectionIDMode, "how sections are anchored: number, by position, or hash, by their content")
+	layoutName := flag.String("layout", layout, "how docs and code are laid out: parallel, side by side, or linear, one after the other")
+	flag.BoolVar(&stickyDocs, "sticky-docs", false, "keep docs in view while scrolling long code blocks")
+	flag.BoolVar(&csp, "csp", false, "write scripts to gocco.js instead of inlining them")
+	flag.StringVar(&outputDir, "output", outputDir, "directory to write the docs into")
+	flag.StringVar(&outputDir, "o", outputDir, "short for -output")
+	flag.BoolVar(&recursive, "recursive", false, "document the sources in directories given as arguments, and below them")
+	flag.BoolVar(&checksums, "checksums", false, "write docs/SHA256SUMS covering the generated files")
+	annotationFormat := flag.String("annotations", "", "also print problems as CI annotations: github")
+	bookFile := flag.String("book", "", "JSON file ordering the sources into parts and chapters")
+	flag.BoolVar(&heatmap, "heatmap", false, "write docs/heatmap.html showing how much of each file is comments")
+	flag.BoolVar(&stats, "stats", false, "write docs/stats.html with the lines, sections and coverage of each file")
+	flag.StringVar(&coverProfile, "coverprofile", "", "coverage profile of go test, for the coverage column of -stats")
+	flag.BoolVar(&strict, "strict", false, "treat warnings as errors, failing the run")
+	flag.BoolVar(&validate, "validate", false, "report broken markup in the docs, like unclosed tags and repeated ids")
+	flag.BoolVar(&socialCards, "social-cards", false, "draw a preview image per page for links shared on social media, needs -base-url")
+	flag.BoolVar(&pkgLinks, "pkg-links", true, "link uses of imported Go packages to pkg.go.dev")
+	flag.BoolVar(&autoIndex, "index", true, "write an index.html listing the files when there are several")
+	indexGroups := flag.String("index-by", indexBy, "group the files on the index page by dir, package or none")
+	flag.BoolVar(&byPackage, "by-package", false, "group the jump menu by package, with an index page for each")
+	flag.BoolVar(&apiPages, "api", false, "add a reference page listing the exported identifiers of each Go package")
+	outNameTemplate := flag.String("out-name", "", "template for the location of pages in docs/, like '{{ .Dir }}/{{ .Name }}.html'")
+	flag.StringVar(&exportFile, "export-sections", "", "write the source lines and page anchor of every section to `file`, as JSON or .csv")
+	flag.BoolVar(&raw, "raw", false, "copy each source next to its page, with a link to download it")
+	flag.Int64Var(&maxDownload, "max-download", maxDownload, "largest source in bytes to download when given an address")
+	flag.IntVar(&jobs, "jobs", jobs, "number of files to work on at once")
+	flag.IntVar(&menuLimit, "menu-limit", menuLimit, "with more sources, jump menus only list the files in the same directory (0 for no limit)")
+	flag.DurationVar(&fileTimeout, "timeout", 0, "give up on a file taking longer than this, like 30s (default no limit)")
+	flag.StringVar(&joinName, "join", "", "document all sources on the single page docs/`name`.html")
+	flag.StringVar(&singleFile, "single-file", "", "write all sources onto one self-contained HTML `file`, with the stylesheet and images inlined")
+	flag.BoolVar(&singlePage, "single-page", false, "document all sources on docs/index.html, opening with a list of the files")
+	flag.StringVar(&siteTitle, "title", "", "name of the site, shown in page titles and headers")
+	flag.StringVar(&siteDescription, "description", "", "description of pages without their own")
+	flag.StringVar(&baseURL, "base-url", "", "address the docs are published at, for canonical links and a 404 page")
+	flag.BoolVar(&search, "search", false, "add a search page over the docs of every file")
+	analyticsFile := flag.String("analytics", "", "file with an analytics snippet to add to every page head")
+	language := flag.String("language", "en", "language of the labels on generated pages")
+	messagesFile := flag.String("messages", "", "JSON file with message catalogs to add or override")
+	dir := flag.String("direction", "auto", "writing direction of the docs: ltr, rtl or auto to detect it per page")
+	flag.BoolVar(&footer, "footer", false, "end pages with the time they were generated")
+	flag.StringVar(&timeFormat, "time-format", timeFormat, "Go time layout of the footer")
+	flag.StringVar(&timeZone, "time-zone", "", "time zone of the footer, like Europe/Berlin (default local)")
+	flag.BoolVar(&tidyWhitespace, "whitespace", false, "report trailing whitespace and mixed indentation in code, and tidy them on the page")
+	flag.IntVar(&tabWidth, "tab-width", tabWidth, "spaces per tab when -whitespace tidies mixed indentation")
+	flag.BoolVar(&checkProse, "prose", false, "report doc comments breaking the prose rules")
+	proseRulesFile := flag.String("prose-rules", ".gocco-prose.json", "file with the prose rules")
+	flag.Int64Var(&inlineImages, "inline-images", 0, "embed images up to this many bytes as data URIs")
+	lang := flag.String("lang", "", "only document these languages, comma separated (e.g. go,python)")
+	allowExec := flag.String("allow-exec", "", "commands gocco:exec directives may run, comma separated (e.g. go,make)")
+	allowEnv := flag.String("env", "", "environment variables templates may read, comma separated")
+	configFile := flag.String("config", "gocco.json", "file with the project configuration")
+	flag.StringVar(&codeStyle, "style", "", "Pygments style of the code, see -list-styles (default the colours of gocco.css)")
+	list := flag.Bool("list-styles", false, "list the styles -style accepts and exit")
+	templateFile := flag.String("template", "", "Go template `file` to render pages with instead of the built-in one")
+	cssFile := flag.String("css", "", "stylesheet `file` to use instead of the built-in gocco.css")
+	marker := flag.String("doc-marker", "", "only comments starting with this right after the comment symbol are docs, like : for //:")
+	flag.BoolVar(&editorServer, "editor-server", false, "answer render requests from an editor, as JSON lines on stdin and stdout")
+	flag.Parse()
+	setSingleFile()
+	if singlePage && joinName == "" {
+		joinName = "index"
+	}
+	if err := loadConfig(*configFile, *configFile != "gocco.json"); err != nil {
+		fail(exitConfig, err)
+	}
+	applyConfig()
+	if *templateFile != "" {
+		if err := setPageTemplate(*templateFile); err != nil {
+			fail(exitConfig, err)
+		}
+	}
+	if *cssFile != "" {
+		if err := setStyleSheet(*cssFile); err != nil {
+			fail(exitConfig, err)
+		}
+	}
+	if err := checkPageTemplate(); err != nil {
+		fail(exitConfig, err)
+	}
+	if *list {
+		listStyles()
 		return
 	}
+	if err := setHighlighter(*highlighterName); err != nil {
+		fail(exitConfig, err)
+	}
+	if err := checkStyle(codeStyle); err != nil {
+		fail(exitConfig, err)
+	}
+	if *marker != "" {
+		setDocMarker(*marker)
+	}
+	setLanguageFilter(*lang)
+	setAllowedEnv(*allowEnv)
+	setAllowedCommands(*allowExec)
+	if *outNameTemplate != "" {
+		if err := setOutName(*outNameTemplate); err != nil {
+			fail(exitConfig, err)
+		}
+	}
+	if err := setAnnotations(*annotationFormat); err != nil {
+		fail(exitConfig, err)
+	}
+	if *messagesFile != "" {
+		if err := loadMessages(*messagesFile); err != nil {
+			fail(exitConfig, err)
+		}
+	}
+	if err := setLanguage(*language); err != nil {
+		fail(exitConfig, err)
+	}
+	if err := setDirection(*dir); err != nil {
+		fail(exitConfig, err)
+	}
+	if err := setIndexBy(*indexGroups); err != nil {
+		fail(exitConfig, err)
+	}
+	if err := setLayout(*layoutName); err != nil {
+		fail(exitConfig, err)
+	}
+	if err := setSectionIDs(*idMode); err != nil {
+		fail(exitConfig, err)
+	}
+	if err := setFormat(*format); err != nil {
+		fail(exitConfig, err)
+	}
+	if outputFormat == "pdf" {
+		if err := findChrome(); err != nil {
+			fail(exitConfig, "-format pdf: ", err)
+		}
+	}
+	if err := setGeneratedAt(); err != nil {
+		fail(exitConfig, err)
+	}
+	if checkProse {
+		if err := loadProseRules(*proseRulesFile, *proseRulesFile != ".gocco-prose.json"); err != nil {
+			fail(exitConfig, err)
+		}
+	}
+	if *analyticsFile != "" {
+		loadAnalytics(*analyticsFile)
+	}
+	if editorServer {
+		commandSettings()
+		runEditorServer()
+		return
+	}
+	if *bookFile != "" {
+		if err := loadBook(*bookFile); err != nil {
+			fail(exitConfig, err)
+		}
+		sources = bookSources(flag.Args())
+	} else {
+		args := flag.Args()
+		// without arguments the sources named in the configuration are
+		// documented, searching the directories among them
+		if len(args) == 0 && len(config.Sources) > 0 {
+			args = configSources()
+			recursive = true
+		}
+		sources = collectSources(args)
+		sort.Strings(sources)
+	}
 
-	ensureDirectory("docs")
-	ioutil.WriteFile("docs/gocco.css", bytes.NewBufferString(Css).Bytes(), 0755)
+	if len(sources) <= 0 {
+		exit()
+	}
+	warnCollisions()
+	findDrafts()
+	orderSources()
+	commandSettings()
+	if singleFile != "" {
+		generateJoined()
+		writePDFs()
+		exit()
+	}
+
+	if err := ensureDirectory(outputDir); err != nil {
+		fail(exitRender, err)
+	}
+	loadAnchors()
+	if outputFormat == "json" {
+		generateJSON()
+		exit()
+	}
+	if err := writeAsset("gocco.css", []byte(Css)); err != nil {
+		renderFailure(err)
+	}
+	if csp {
+		if err := writeAsset("gocco.js", []byte(Js)); err != nil {
+			renderFailure(err)
+		}
+	}
+	if err := writeHighlightJS(); err != nil {
+		renderFailure(err)
+	}
+
+	var packages []*APIPackage
+	if apiPages {
+		packages = prepareAPIPages()
+	}
+	if joinName != "" {
+		generateJoined()
+	} else {
+		forEachSource(func(i int, source string) {
+			command.generateDocumentation(source)
+		})
+	}
+	if holdingPages() {
+		if broken := releasePages(laterPages(packages)); broken > 0 {
+			fail(exitProblems, broken, " broken links, no pages written")
+		}
+	}
+	writePDFs()
 
-	wg := new(sync.WaitGroup)
-	wg.Add(flag.NArg())
-	for _, arg := range flag.Args() {
-		go generateDocumentation(arg, wg)
+	if apiPages {
+		writeAPIPages(packages)
+	}
+	if heatmap {
+		writeHeatmap()
+	}
+	if stats {
+		writeStats()
+	}
+	if joinName == "" && indexPage() != "" {
+		writeContents()
+	}
+	if byPackage {
+		writePackageIndexes()
+	}
+	if baseURL != "" {
+		writeNotFound()
+	}
+	if search {
+		writeSearch()
+	}
+	if exportFile != "" {
+		writeSectionExport()
+	}
+	writeAnchors()
+	writeManifest()
+	if checksums {
+		writeChecksums()
+	}
+	if dangling := atomic.LoadInt32(&danglingReferences); dangling > 0 {
+		log.Println("gocco: ", dangling, " dangling references")
+	}
+	if watch {
+		watchSources()
 	}
-	wg.Wait()
+	exit()
 }
@@ -0,0 +1,76 @@
+package gocco
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGetLanguage(t *testing.T) {
+	setupOnce.Do(setup)
+	tests := []struct {
+		source string
+		name   string
+	}{
+		{"gocco.go", "go"},
+		{"GOCCO.GO", "go"},
+		{"cmd/gocco/main.go", "go"},
+		{"script.py", "python"},
+		{"archive.tar.sh", "bash"},
+		// extensionless files and dotfiles have no language to go by
+		{"Makefile", ""},
+		{".bashrc", ""},
+		{"dir.go/README", ""},
+		{"src/lib/x.go", "go"},
+	}
+	for _, test := range tests {
+		source := filepath.FromSlash(test.source)
+		name := ""
+		if language := getLanguage(source); language != nil {
+			name = language.name
+		}
+		if name != test.name {
+			t.Errorf("getLanguage(%q) = %q, want %q", source, name, test.name)
+		}
+	}
+}
+
+// The sources and pages are given with slashes, and use the separator of
+// the system the tests run on
+func TestDestination(t *testing.T) {
+	defer func(dir string) { outputDir = dir }(outputDir)
+	outputDir = "docs"
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"gocco.go", "docs/gocco.html"},
+		{"./gocco.go", "docs/gocco.html"},
+		{"lib/x.go", "docs/lib/x.html"},
+		{"lib/./y/../x.go", "docs/lib/x.html"},
+		// only the last extension goes
+		{"archive.tar.sh", "docs/archive.tar.html"},
+		// extensionless files and dotfiles keep their whole name
+		{"Makefile", "docs/Makefile.html"},
+		{"bin/run", "docs/bin/run.html"},
+		{".bashrc", "docs/.bashrc.html"},
+		{"conf/.profile", "docs/conf/.profile.html"},
+		{"lib/sub/.bashrc", "docs/lib/sub/.bashrc.html"},
+	}
+	for _, test := range tests {
+		source := filepath.FromSlash(test.source)
+		if got, want := destination(source), filepath.FromSlash(test.want); got != want {
+			t.Errorf("destination(%q) = %q, want %q", source, got, want)
+		}
+	}
+	// a directory outside the working directory, like `/src` or
+	// `C:\src`, gets a flat name of its own
+	source, err := filepath.Abs(filepath.FromSlash("/src/x.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dest := destination(source)
+	if dir := filepath.Dir(dest); filepath.Dir(dir) != "docs" || !strings.HasSuffix(dir, "src") || filepath.Base(dest) != "x.html" {
+		t.Errorf("destination(%q) = %q, want docs/<flat name>/x.html", source, dest)
+	}
+}
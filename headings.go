@@ -0,0 +1,166 @@
+package gocco
+
+import (
+	"container/list"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ## Headings
+
+// a `Heading` is a markdown heading found in the docs of a page
+type Heading struct {
+	Level int
+	// The anchor the heading can be linked with
+	ID string
+	// The plain text of the heading, without markup
+	Text string
+	// the source the heading is in, and its id on a page of that source
+	// alone, which differs from `ID` on a joined page
+	source, local string
+}
+
+// matches the headings blackfriday renders, with an explicit id if the
+// author gave one using `{#id}`
+var headingPattern = regexp.MustCompile(`(?s)<h([1-6])(?: id="([^"]*)")?>(.*?)</h[1-6]>`)
+
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+var slugPattern = regexp.MustCompile(`[^\pL\pN]+`)
+
+// turn the text of a heading into an anchor, the way GitHub does
+func slugify(text string) string {
+	return strings.Trim(slugPattern.ReplaceAllString(strings.ToLower(text), "-"), "-")
+}
+
+// `anchorHeadings` gives every heading in the rendered docs of a page an
+// id, unique within the page, and a link to itself that shows on hover.
+// The headings are returned in order so they can be listed elsewhere
+func anchorHeadings(sections *list.List) []*Heading {
+	var headings []*Heading
+	used := make(map[string]int)
+	usedBySource := make(map[string]map[string]int)
+	// repeated headings get a numeric suffix
+	unique := func(used map[string]int, id string) string {
+		if n := used[id]; n > 0 {
+			used[id] = n + 1
+			return fmt.Sprintf("%s-%d", id, n)
+		}
+		used[id] = 1
+		return id
+	}
+	for e := sections.Front(); e != nil; e = e.Next() {
+		section := e.Value.(*Section)
+		if usedBySource[section.source] == nil {
+			usedBySource[section.source] = make(map[string]int)
+		}
+		section.DocsHTML = headingPattern.ReplaceAllFunc(section.DocsHTML, func(match []byte) []byte {
+			parts := headingPattern.FindSubmatch(match)
+			text := html.UnescapeString(tagPattern.ReplaceAllString(string(parts[3]), ""))
+			id := string(parts[2])
+			if id == "" {
+				id = slugify(text)
+			}
+			if id == "" {
+				id = "heading"
+			}
+			local := unique(usedBySource[section.source], id)
+			id = unique(used, id)
+			level := int(parts[1][0] - '0')
+			headings = append(headings, &Heading{level, id, text, section.source, local})
+			return []byte(fmt.Sprintf(`<h%d id="%s"><a class="anchor" href="#%s">#</a>%s</h%d>`,
+				level, id, id, parts[3], level))
+		})
+	}
+	return headings
+}
+
+// ## Demoting headings
+
+// The title of a page is its only `<h1>`, so the outline screen readers
+// and search engines draw from the headings starts there. A comment
+// written as a file of its own, starting with `# Heading`, adds a second
+// one. `"heading_offset": 1` in the `markdown` part of the configuration
+// turns the `<h1>` of the docs into `<h2>`, the `<h2>` into `<h3>` and so
+// on, and a page sets its own in its front matter:
+//
+//     // ---
+//     // heading_offset: 1
+//     // ---
+//
+// Headings can't go deeper than `<h6>`, where demoted ones stop
+var headingTagPattern = regexp.MustCompile(`<(/?)h([1-6])\b`)
+
+// the rendered docs `html` with their headings demoted by `offset` levels
+func demoteHeadings(html []byte, offset int) []byte {
+	if offset <= 0 {
+		return html
+	}
+	return headingTagPattern.ReplaceAllFunc(html, func(match []byte) []byte {
+		parts := headingTagPattern.FindSubmatch(match)
+		level := int(parts[2][0]-'0') + offset
+		if level > 6 {
+			level = 6
+		}
+		return []byte(fmt.Sprintf("<%sh%d", parts[1], level))
+	})
+}
+
+// the heading offset of a page, its front matter's or the configured
+func (g *Generator) headingOffset(source string, meta FrontMatter) int {
+	offset := g.Markdown.HeadingOffset
+	if value, ok := meta["heading_offset"]; ok {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || n > 5 {
+			warn(source, 0, "heading_offset is "+value+", use 0 to 5")
+			return offset
+		}
+		offset = n
+	}
+	return offset
+}
+
+// a `[[toc]]` line in the docs, once it has been through markdown
+var tocPattern = regexp.MustCompile(`<p>\s*\[\[toc\]\]\s*</p>`)
+
+// `insertTOC` replaces every `[[toc]]` marker in the docs with a nested
+// list linking to the headings of the page
+func insertTOC(sections *list.List, headings []*Heading) {
+	toc := []byte(renderTOC(headings))
+	for e := sections.Front(); e != nil; e = e.Next() {
+		section := e.Value.(*Section)
+		section.DocsHTML = tocPattern.ReplaceAllLiteral(section.DocsHTML, toc)
+	}
+}
+
+// render the headings as nested lists, a deeper heading opening a list
+// inside the entry of the heading before it
+func renderTOC(headings []*Heading) string {
+	if len(headings) == 0 {
+		return ""
+	}
+	buf := new(strings.Builder)
+	buf.WriteString(`<nav class="toc">`)
+	var levels []int
+	for _, h := range headings {
+		for len(levels) > 0 && levels[len(levels)-1] > h.Level {
+			buf.WriteString("</li></ul>")
+			levels = levels[:len(levels)-1]
+		}
+		if len(levels) > 0 && levels[len(levels)-1] == h.Level {
+			buf.WriteString("</li><li>")
+		} else {
+			buf.WriteString("<ul><li>")
+			levels = append(levels, h.Level)
+		}
+		fmt.Fprintf(buf, `<a href="#%s">%s</a>`, h.ID, html.EscapeString(h.Text))
+	}
+	for range levels {
+		buf.WriteString("</li></ul>")
+	}
+	buf.WriteString("</nav>")
+	return buf.String()
+}
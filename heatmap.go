@@ -0,0 +1,154 @@
+package gocco
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ## Documentation heat map
+
+// Which parts of a code base are explained, and which are left to the
+// reader? With `-heatmap` gocco writes `docs/heatmap.html`, a treemap of
+// the sources where every file takes room by its number of lines and is
+// colored by its share of comment lines, from red for none to green,
+// with directories summing up their files
+var heatmap bool
+
+// the comment and code lines of each source, and its number of sections,
+// counted while parsing
+var (
+	densities     = make(map[string][3]int)
+	densitiesLock sync.Mutex
+)
+
+// count the non-blank doc and code lines of a parsed source
+func recordDensity(source string, sections *list.List) {
+	docs, code := 0, 0
+	for e := sections.Front(); e != nil; e = e.Next() {
+		section := e.Value.(*Section)
+		docs += nonBlankLines(section.docsText)
+		code += nonBlankLines(section.codeText)
+	}
+	densitiesLock.Lock()
+	densities[source] = [3]int{docs, code, sections.Len()}
+	densitiesLock.Unlock()
+}
+
+func nonBlankLines(text []byte) int {
+	n := 0
+	for _, line := range bytes.Split(text, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// a `HeatNode` is a file or a directory of the map
+type HeatNode struct {
+	Name     string
+	Href     string
+	Docs     int
+	Code     int
+	Children []*HeatNode
+	// Whether the children are laid out top to bottom rather than
+	// side by side, alternating by depth
+	Columns bool
+}
+
+// the total number of lines, which sets the room a node takes
+func (n *HeatNode) Lines() int {
+	return n.Docs + n.Code
+}
+
+// the share of comment lines, in percent
+func (n *HeatNode) Density() int {
+	if n.Lines() == 0 {
+		return 0
+	}
+	return 100 * n.Docs / n.Lines()
+}
+
+// the color of a node: a third of the lines being comments is plenty,
+// and as green as it gets
+func (n *HeatNode) Color() string {
+	hue := n.Density() * 120 / 33
+	if hue > 120 {
+		hue = 120
+	}
+	return fmt.Sprintf("hsl(%d, 65%%, 78%%)", hue)
+}
+
+// `heatTree` arranges the sources into a tree of directories
+func heatTree() *HeatNode {
+	root := &HeatNode{Name: "."}
+	dirs := map[string]*HeatNode{".": root}
+	// find or make the node of a directory, and those above it
+	var dirNode func(dir string) *HeatNode
+	dirNode = func(dir string) *HeatNode {
+		if node, ok := dirs[dir]; ok {
+			return node
+		}
+		node := &HeatNode{Name: filepath.Base(dir)}
+		parent := dirNode(filepath.Dir(dir))
+		parent.Children = append(parent.Children, node)
+		dirs[dir] = node
+		return node
+	}
+	densitiesLock.Lock()
+	defer densitiesLock.Unlock()
+	for _, source := range sources {
+		counts, ok := densities[source]
+		if !ok {
+			continue
+		}
+		dir := filepath.Clean(filepath.Dir(source))
+		if filepath.IsAbs(dir) || strings.HasPrefix(dir, "..") {
+			dir = filepath.ToSlash(dir)
+		}
+		file := &HeatNode{filepath.Base(source), pagePath(source), counts[0], counts[1], nil, false}
+		dirNode(dir).Children = append(dirNode(dir).Children, file)
+	}
+	sumUp(root)
+	return root
+}
+
+// add the lines of the children to each directory, and put the biggest
+// children first
+func sumUp(node *HeatNode) {
+	for _, child := range node.Children {
+		if child.Children != nil {
+			child.Columns = !node.Columns
+			sumUp(child)
+		}
+		node.Docs += child.Docs
+		node.Code += child.Code
+	}
+	sort.SliceStable(node.Children, func(i, j int) bool {
+		return node.Children[i].Lines() > node.Children[j].Lines()
+	})
+}
+
+func writeHeatmap() {
+	html, err := renderTemplate("heatmap", HeatmapHTML, map[string]interface{}{
+		"Title":     siteName(),
+		"Root":      heatTree(),
+		"Index":     indexPage(),
+		"Analytics": analytics,
+	})
+	dest := filepath.Join(outputDir, "heatmap.html")
+	if err == nil {
+		err = writeFile(dest, html)
+	}
+	if err != nil {
+		renderFailure(err)
+		return
+	}
+	log.Println("gocco: heat map -> ", dest)
+}
@@ -0,0 +1,105 @@
+package gocco
+
+import (
+	"container/list"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ## Highlighting in the browser
+
+// Pygments runs once per source, which on a repository of thousands of
+// files is most of the build. With `-highlighter client` gocco leaves the
+// code as it is, escaped in a `<pre><code class="language-go">`, and the
+// pages load [highlight.js](https://highlightjs.org) to colour it when
+// they're read. The pages get bigger and slower to show, the build gets
+// much faster and no longer needs Pygments.
+//
+// highlight.js comes from `-highlight-js`, the address of a release,
+// which has `highlight.min.js` and the themes under `styles/`. That can
+// also be a local copy, which is then written into `docs/highlight/` so
+// the docs work offline. The theme is the `-style`, as highlight.js and
+// Pygments share most names, or `default`
+var highlighter = "pygments"
+
+// where highlight.js comes from
+var highlightJS = "https://cdnjs.cloudflare.com/ajax/libs/highlight.js/11.9.0"
+
+// check the value of `-highlighter`
+func setHighlighter(value string) error {
+	switch value {
+	case "pygments", "client":
+		highlighter = value
+		return nil
+	}
+	return fmt.Errorf("unknown -highlighter %q, use pygments or client", value)
+}
+
+// whether the code is left to the browser to highlight
+func clientHighlighting() bool {
+	return highlighter == "client"
+}
+
+// the highlight.js theme of the pages
+func (g *Generator) highlightTheme() string {
+	if g.Style != "" {
+		return g.Style
+	}
+	return "default"
+}
+
+// whether highlight.js is copied into the docs rather than linked
+func localHighlightJS() bool {
+	return !strings.Contains(highlightJS, "://")
+}
+
+// `writeHighlightJS` copies a local highlight.js and its theme into
+// `docs/highlight/`
+func writeHighlightJS() error {
+	if !clientHighlighting() || !localHighlightJS() {
+		return nil
+	}
+	for _, file := range []string{"highlight.min.js", "styles/" + command.highlightTheme() + ".min.css"} {
+		data, err := ioutil.ReadFile(filepath.Join(highlightJS, filepath.FromSlash(file)))
+		if err != nil {
+			return fmt.Errorf("-highlight-js: %v", err)
+		}
+		if err := writeAsset(path.Join("highlight", path.Base(file)), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// the addresses of the highlight.js script and theme for a page whose
+// way back to the output directory is `root`, empty when Pygments does
+// the highlighting
+func (g *Generator) highlightAssets(root string) (script, style string) {
+	if !clientHighlighting() {
+		return "", ""
+	}
+	theme := g.highlightTheme() + ".min.css"
+	if localHighlightJS() {
+		return root + assetName("highlight/highlight.min.js"), root + assetName("highlight/"+theme)
+	}
+	base := strings.TrimSuffix(highlightJS, "/")
+	return base + "/highlight.min.js", base + "/styles/" + theme
+}
+
+// `highlightClient` puts the code of every section into the page as it
+// is, marked with its language for highlight.js
+func (g *Generator) highlightClient(source string, sections *list.List) {
+	start := fmt.Sprintf(`%s<code class="language-%s">`, highlightStart, getLanguage(source).name)
+	for e := sections.Front(); e != nil; e = e.Next() {
+		section := e.Value.(*Section)
+		code := string(section.codeText)
+		if g.Code.TabWidth > 0 {
+			code = expandAllTabs(code, g.Code.TabWidth)
+		}
+		section.CodeHTML = []byte(start + html.EscapeString(code) + "</code>" + highlightEnd)
+	}
+}
@@ -0,0 +1,102 @@
+package gocco
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+)
+
+// ## The index page
+
+// A run over several files also writes `docs/index.html`, listing them
+// all, so the docs have a page to land on besides the jump menus. The
+// files are grouped with `-index-by`: by their directory, by their Go
+// package, which tells apart the `main` packages of `cmd/a` and `cmd/b`,
+// or not at all. `-index=false` leaves the page out, and so does a
+// source whose own page is `index.html`. Books and `-by-package` have
+// contents pages of their own
+var (
+	autoIndex = true
+	indexBy   = "dir"
+)
+
+// whether some source's page is `docs/index.html`, found with the
+// collisions
+var indexTaken bool
+
+// check the value of `-index-by`
+func setIndexBy(value string) error {
+	switch value {
+	case "dir", "package", "none":
+		indexBy = value
+		return nil
+	}
+	return fmt.Errorf("unknown -index-by %q, use dir, package or none", value)
+}
+
+// whether the index page lists the files of this run
+func listsFiles() bool {
+	return autoIndex && joinName == "" && !indexTaken && len(listedSources(sources)) > 1
+}
+
+// `indexParts` groups the listed sources for the index page, in the
+// order the groups first appear, except that the files of the working
+// directory come first as they go without a heading. So does a single
+// group
+func indexParts() []*Part {
+	var parts []*Part
+	groups := make(map[string]*Part)
+	for _, source := range listedSources(sources) {
+		key, title := "", ""
+		switch indexBy {
+		case "dir":
+			key = filepath.ToSlash(filepath.Dir(source))
+			title = key
+		case "package":
+			key, title = packageGroup(source)
+		}
+		if title == "." {
+			title = ""
+		}
+		part := groups[key]
+		if part == nil {
+			part = &Part{Title: title}
+			groups[key] = part
+			if title == "" {
+				parts = append([]*Part{part}, parts...)
+			} else {
+				parts = append(parts, part)
+			}
+		}
+		part.Chapters = append(part.Chapters, source)
+	}
+	if len(parts) == 1 {
+		parts[0].Title = ""
+	}
+	return parts
+}
+
+// the package group of a source and its heading, the package name and
+// the directory unless they're the same. Files other than Go go by their
+// directory
+func packageGroup(source string) (string, string) {
+	dir := filepath.ToSlash(filepath.Dir(source))
+	if filepath.Ext(source) != ".go" {
+		return dir, dir
+	}
+	code, err := readSource(source)
+	if err != nil {
+		return dir, dir
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), source, code, parser.PackageClauseOnly)
+	if err != nil {
+		return dir, dir
+	}
+	name := strings.TrimSuffix(file.Name.Name, "_test")
+	if dir == "." || filepath.Base(dir) == name {
+		return dir + "\x00" + name, name
+	}
+	return dir + "\x00" + name, name + " (" + dir + ")"
+}
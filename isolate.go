@@ -0,0 +1,42 @@
+package gocco
+
+import (
+	"fmt"
+	"time"
+)
+
+// ## Isolating files
+
+// One pathological file, one that makes a highlighter or the markdown
+// renderer panic or take forever, shouldn't cost the documentation of
+// all the others. Each file is worked on behind a `recover`, and with
+// `-timeout` given up on once it takes too long. It is then skipped with
+// a render failure; whatever it was still doing is abandoned, and as
+// only the caller of `isolate` writes files, never written
+var fileTimeout time.Duration
+
+// `isolate` runs `work` for `source`, turning a panic or running out of
+// time into an error
+func isolate(source string, work func()) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("%s: panic: %v", source, r)
+			}
+		}()
+		work()
+		done <- nil
+	}()
+	if fileTimeout <= 0 {
+		return <-done
+	}
+	timer := time.NewTimer(fileTimeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return fmt.Errorf("%s: timed out after %v", source, fileTimeout)
+	}
+}
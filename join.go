@@ -0,0 +1,99 @@
+package gocco
+
+import (
+	"container/list"
+	"math"
+	"path/filepath"
+)
+
+// ## Joining files
+
+// With `-join name` the sources are documented as one narrative on the
+// single page `docs/name.html`, like Docco does for a set of files. Each
+// file opens with a heading, and sections are numbered straight through
+var joinName string
+
+// `-single-page` publishes the classic annotated source as one page: the
+// sources are joined into `docs/index.html`, which opens with a list of
+// the files leading to their headings
+var singlePage bool
+
+// `-single-file report.html` goes one step further, for docs to be
+// mailed or attached to a release: the single page is written to that
+// file alone, with the stylesheet and every image inlined, and nothing
+// goes into `docs/`
+var singleFile string
+
+// put `-single-file` into effect, after the flags are read
+func setSingleFile() {
+	if singleFile == "" {
+		return
+	}
+	singlePage = true
+	joinName = "index"
+	inlineImages = math.MaxInt64
+	// the scripts have to be inline too, and the links of the page
+	// can't lead anywhere but into it
+	csp = false
+	checkLinks = false
+}
+
+// the stylesheet to put into the page itself rather than link to
+func inlineCSS() string {
+	if singleFile == "" {
+		return ""
+	}
+	return Css
+}
+
+// the files listed at the top of a single page
+func (g *Generator) fileContents() []*JumpLink {
+	if !singlePage {
+		return nil
+	}
+	files := make([]*JumpLink, len(g.Sources))
+	for i, source := range g.Sources {
+		files[i] = &JumpLink{filepath.ToSlash(source), "#" + fileAnchor(source), ""}
+	}
+	return files
+}
+
+// the anchor of a file's heading on a joined page
+func fileAnchor(source string) string {
+	return "file-" + slugify(filepath.ToSlash(source))
+}
+
+func generateJoined() {
+	// the files are still read and highlighted in parallel, but put
+	// together in order
+	parts := make([]*list.List, len(sources))
+	forEachSource(func(i int, source string) {
+		// work given up on mustn't touch `parts` any more, so the
+		// sections are handed over rather than stored
+		loaded := make(chan *list.List, 1)
+		err := isolate(source, func() {
+			sections, _, err := command.loadSections(source)
+			if err != nil {
+				inputError(err)
+				return
+			}
+			loaded <- sections
+		})
+		if err != nil {
+			renderFailure(err)
+			return
+		}
+		select {
+		case parts[i] = <-loaded:
+		default:
+		}
+	})
+
+	all := new(list.List)
+	for _, part := range parts {
+		if part != nil {
+			all.PushBackList(part)
+		}
+	}
+	command.generateHTML(joinName, all, FrontMatter{})()
+}
@@ -0,0 +1,89 @@
+package gocco
+
+// ## Languages
+
+// The languages gocco knows out of the box, by file extension: the name
+// Pygments highlights them under and the symbol starting a line comment,
+// if it has any. Their block comments are in `blockComments`
+var builtinLanguages = map[string][2]string{
+	".go": {"go", "//"},
+
+	// C and its descendants
+	".c":      {"c", "//"},
+	".h":      {"c", "//"},
+	".cc":     {"cpp", "//"},
+	".cpp":    {"cpp", "//"},
+	".cxx":    {"cpp", "//"},
+	".hh":     {"cpp", "//"},
+	".hpp":    {"cpp", "//"},
+	".m":      {"objective-c", "//"},
+	".cs":     {"csharp", "//"},
+	".d":      {"d", "//"},
+	".java":   {"java", "//"},
+	".kt":     {"kotlin", "//"},
+	".scala":  {"scala", "//"},
+	".groovy": {"groovy", "//"},
+	".swift":  {"swift", "//"},
+	".rs":     {"rust", "//"},
+	".zig":    {"zig", "//"},
+	".dart":   {"dart", "//"},
+	".fs":     {"fsharp", "//"},
+	".php":    {"php", "//"},
+	".proto":  {"protobuf", "//"},
+	".v":      {"verilog", "//"},
+
+	// the web
+	".js":     {"javascript", "//"},
+	".mjs":    {"javascript", "//"},
+	".cjs":    {"javascript", "//"},
+	".jsx":    {"javascript", "//"},
+	".ts":     {"typescript", "//"},
+	".tsx":    {"typescript", "//"},
+	".coffee": {"coffeescript", "#"},
+
+	// scripting
+	".py":   {"python", "#"},
+	".rb":   {"ruby", "#"},
+	".pl":   {"perl", "#"},
+	".pm":   {"perl", "#"},
+	".sh":   {"bash", "#"},
+	".bash": {"bash", "#"},
+	".zsh":  {"bash", "#"},
+	".ps1":  {"powershell", "#"},
+	".tcl":  {"tcl", "#"},
+	".awk":  {"awk", "#"},
+	".lua":  {"lua", "--"},
+	".r":    {"r", "#"},
+	".jl":   {"julia", "#"},
+	".ex":   {"elixir", "#"},
+	".exs":  {"elixir", "#"},
+	".erl":  {"erlang", "%"},
+	".nim":  {"nim", "#"},
+	".cr":   {"crystal", "#"},
+
+	// functional
+	".hs":   {"haskell", "--"},
+	".elm":  {"elm", "--"},
+	".clj":  {"clojure", ";"},
+	".cljs": {"clojure", ";"},
+	".lisp": {"common-lisp", ";"},
+	".scm":  {"scheme", ";"},
+	".el":   {"emacs-lisp", ";"},
+
+	// data, configuration and hardware
+	".sql":   {"sql", "--"},
+	".yaml":  {"yaml", "#"},
+	".yml":   {"yaml", "#"},
+	".toml":  {"toml", "#"},
+	".tf":    {"terraform", "#"},
+	".nix":   {"nix", "#"},
+	".cmake": {"cmake", "#"},
+	".vhd":   {"vhdl", "--"},
+	".adb":   {"ada", "--"},
+	".ads":   {"ada", "--"},
+	".f90":   {"fortran", "!"},
+	".css":   {"css", ""},
+	".html":  {"html", ""},
+	".htm":   {"html", ""},
+	".xml":   {"xml", ""},
+}
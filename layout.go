@@ -0,0 +1,173 @@
+package gocco
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// ## Layout
+
+// Pages mirror the directories of their sources under `docs/`, so
+// `cmd/a/main.go` and `cmd/b/main.go` get pages of their own. For a
+// module with many packages there is `-by-package` too: the jump menu is
+// grouped by package, and every package gets an index page listing its
+// files
+var byPackage bool
+
+// the directory under `docs/` holding the pages of the sources in `dir`.
+// Directories outside the working directory can't be mirrored, they get
+// a flat name instead
+func pageDirOf(dir string) string {
+	dir = filepath.Clean(dir)
+	switch {
+	case dir == ".":
+		return ""
+	case filepath.IsAbs(dir) || strings.HasPrefix(dir, ".."):
+		return slugify(filepath.ToSlash(dir))
+	}
+	return dir
+}
+
+// the location of a source's page relative to `docs/`, as used in links
+func pagePath(source string) string {
+	rel, err := filepath.Rel(outputDir, destination(source))
+	if err != nil {
+		return filepath.ToSlash(destination(source))
+	}
+	return filepath.ToSlash(rel)
+}
+
+// the address of the file `to` relative to the file `from`, both given
+// as paths including `docs/`
+func relativeLink(from, to string) string {
+	rel, err := filepath.Rel(filepath.Dir(from), to)
+	if err != nil {
+		return filepath.ToSlash(to)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// the prefix leading from the page at `page` back to `docs/`, empty for
+// pages right inside it
+func rootOf(page string) string {
+	root := relativeLink(page, outputDir)
+	if root == "." {
+		return ""
+	}
+	return root + "/"
+}
+
+// `warnCollisions` reports sources whose pages would end up at the same
+// place in `docs/`, the later overwriting the earlier
+func warnCollisions() {
+	if joinName != "" {
+		return
+	}
+	pages := make(map[string]string)
+	for _, source := range sources {
+		dest := destination(source)
+		if other, ok := pages[dest]; ok {
+			warn(source, 0, "its page "+dest+" is also the page of "+other)
+			continue
+		}
+		pages[dest] = source
+	}
+	_, indexTaken = pages[filepath.Join(outputDir, "index.html")]
+}
+
+// the package directory starting at `sources[i]`, if the jump menu or
+// the index page is grouped by package and a new one starts there
+func (g *Generator) packageStarting(i int) string {
+	if !byPackage && !g.shardedMenu() {
+		return ""
+	}
+	dir := filepath.Dir(g.Sources[i])
+	if i > 0 && filepath.Dir(g.Sources[i-1]) == dir {
+		return ""
+	}
+	return filepath.ToSlash(dir)
+}
+
+// the sources grouped into one part per package, for the index page
+func packageParts() []*Part {
+	var parts []*Part
+	for i, source := range sources {
+		if dir := command.packageStarting(i); dir != "" {
+			parts = append(parts, &Part{Title: dir})
+		}
+		if !drafts[source] {
+			parts[len(parts)-1].Chapters = append(parts[len(parts)-1].Chapters, source)
+		}
+	}
+	return parts
+}
+
+// ## Output names
+
+// `-out-name` takes a template for a page's location under `docs/`,
+// replacing the default of the source's name with `.html`. The template
+// sees the fields of `OutName` and can use `slug` to make text safe for
+// URLs and `flatten` to turn a path into a single name, as in
+// `{{ flatten .Dir }}-{{ .Name }}.html`
+var outName *template.Template
+
+// what an `-out-name` template knows about a source
+type OutName struct {
+	Path    string // the source as given, with slashes
+	Dir     string // its directory, `.` for the working directory
+	Name    string // its file name without the extension
+	Ext     string // the extension without the dot, like `go`
+	Package string // the directory its page goes into with `-by-package`
+}
+
+// parse the `-out-name` template, trying it on a made up source so that
+// mistakes show up before any page is written
+func setOutName(text string) error {
+	t, err := template.New("out-name").Funcs(template.FuncMap{
+		"slug": slugify,
+		"flatten": func(path string) string {
+			return strings.Replace(strings.Trim(path, "./"), "/", "-", -1)
+		},
+	}).Parse(text)
+	if err != nil {
+		return err
+	}
+	outName = t
+	if _, err := namedDestination("pkg/example.go"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// the location of a source's page given by the `-out-name` template
+func namedDestination(source string) (string, error) {
+	source = filepath.Clean(source)
+	base := filepath.Base(source)
+	ext := filepath.Ext(base)
+	name := strings.TrimSuffix(base, ext)
+	if name == "" {
+		name = base
+	}
+	var out bytes.Buffer
+	err := outName.Execute(&out, &OutName{
+		Path:    filepath.ToSlash(source),
+		Dir:     filepath.ToSlash(filepath.Dir(source)),
+		Name:    name,
+		Ext:     strings.TrimPrefix(ext, "."),
+		Package: filepath.ToSlash(pageDirOf(filepath.Dir(source))),
+	})
+	if err != nil {
+		return "", err
+	}
+	// an empty directory, like `flatten .Dir` of the working directory,
+	// leaves a leading slash
+	page := path.Clean(strings.TrimLeft(strings.TrimSpace(out.String()), "/"))
+	if page == "." || page == ".." || strings.HasPrefix(page, "../") {
+		return "", fmt.Errorf("-out-name gives %q for %s, which is outside %s", page, source, outputDir)
+	}
+	return filepath.Join(outputDir, filepath.FromSlash(page)), nil
+}
@@ -0,0 +1,169 @@
+package gocco
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ## Using gocco as a library
+
+// Tools can document sources without going through the command. `Parse`
+// splits a source into its sections and `Generate` renders the page the
+// command would write into `docs/`. The page links to `gocco.css`, whose
+// contents are `Css`.
+//
+// The settings a page is rendered with, like the sources its menu lists
+// and how its markdown is read, belong to a `Generator`, which every
+// stage of the pipeline reads them from. Callers with settings of their
+// own make one with `NewGenerator`, and generators are safe to use from
+// any number of goroutines at once. The command keeps its own in
+// `command`, filled from the flags. The languages are shared by all, but
+// only read once set up.
+//
+// Only the command's generator writes files: besides its pages, the
+// copies of images and videos, social cards, the search index and the
+// anchors the next build redirects. The checks and statistics its
+// flags ask for are its own too. Other generators leave images and
+// videos linked as written
+
+// `Options` describe the source given to `Generate`
+type Options struct {
+	// Name of the source. Its extension decides the language, and it
+	// titles the page
+	Filename string
+	// Title of the page instead of the file name
+	Title string
+	// Pygments style of the code instead of the default
+	Style string
+}
+
+// the languages are set up once, by whichever comes first of `Main` and
+// the library functions
+var setupOnce sync.Once
+
+// a `Generator` documents sources with settings of its own
+type Generator struct {
+	// The sources of the project, which the jump menu lists and the
+	// previous and next links follow. Empty for pages on their own
+	Sources []string
+	// Pygments style of the pages without one of their own, instead of
+	// the colours of `gocco.css`
+	Style string
+	// `parallel` or `linear`, see `-layout`
+	Layout string
+	// How the docs are rendered, see `MarkdownOptions`
+	Markdown MarkdownOptions
+	// How the code is formatted, see `CodeOptions`
+	Code CodeOptions
+	// Link uses of imported Go packages to pkg.go.dev, see `-pkg-links`
+	PkgLinks bool
+	// Repeat the summary of a section every this many lines of its
+	// code, see `-balance`
+	Balance int
+
+	// whether this is the command's generator, see above
+	cli bool
+	// the headings of each source, by anchor, worked out once
+	headings     map[string]map[string]string
+	headingsLock sync.Mutex
+}
+
+// `NewGenerator` returns a generator for a project of `sources`, with the
+// settings the command has without flags or configuration
+func NewGenerator(sources ...string) *Generator {
+	return &Generator{Sources: sources, Layout: "parallel", Markdown: defaultMarkdown, PkgLinks: true}
+}
+
+// the generator of the package functions
+var packageGenerator = NewGenerator()
+
+// the generator of the command, see `commandSettings`
+var command = NewGenerator()
+
+// `commandSettings` hands the settings of the flags and the configuration
+// to `command`, once the sources are known
+func commandSettings() {
+	command.Sources = sources
+	command.Style = codeStyle
+	command.Layout = layout
+	command.Markdown = config.Markdown
+	command.Code = config.Code
+	command.PkgLinks = pkgLinks
+	command.Balance = balanceLines
+	command.cli = true
+}
+
+// `Parse` splits a source into its sections, with their docs and code as
+// written
+func Parse(filename string, src []byte) ([]*Section, error) {
+	return packageGenerator.Parse(filename, src)
+}
+
+// `Parse` splits a source into its sections, see the function `Parse`
+func (g *Generator) Parse(filename string, src []byte) ([]*Section, error) {
+	setupOnce.Do(setup)
+	if getLanguage(filename) == nil {
+		return nil, fmt.Errorf("%s: no language known", filename)
+	}
+	var sections []*Section
+	for e := parse(filename, src).Front(); e != nil; e = e.Next() {
+		sections = append(sections, e.Value.(*Section))
+	}
+	return sections, nil
+}
+
+// the docs of a section, without the comment markers
+func (s *Section) Docs() string {
+	return string(s.docsText)
+}
+
+// the code of a section
+func (s *Section) Code() string {
+	return string(s.codeText)
+}
+
+// the line of its source the section starts on
+func (s *Section) Line() int {
+	return s.line
+}
+
+// the line of its source the section ends on
+func (s *Section) EndLine() int {
+	return s.end
+}
+
+// `Generate` renders the page of a source
+func Generate(src []byte, opts Options) ([]byte, error) {
+	return packageGenerator.Generate(src, opts)
+}
+
+// `Generate` renders the page of a source, see the function `Generate`
+func (g *Generator) Generate(src []byte, opts Options) ([]byte, error) {
+	setupOnce.Do(setup)
+	if getLanguage(opts.Filename) == nil {
+		return nil, fmt.Errorf("%s: no language known", opts.Filename)
+	}
+	// work given up on by `isolate` mustn't touch the page any more, so
+	// it's handed over rather than stored
+	type rendered struct {
+		html []byte
+		err  error
+	}
+	done := make(chan rendered, 1)
+	err := isolate(opts.Filename, func() {
+		sections, meta := g.prepareSections(opts.Filename, src)
+		if opts.Title != "" {
+			meta["title"] = opts.Title
+		}
+		if opts.Style != "" {
+			meta["style"] = opts.Style
+		}
+		_, html, err := g.renderPage(opts.Filename, sections, meta)
+		done <- rendered{html, err}
+	})
+	if err != nil {
+		return nil, err
+	}
+	page := <-done
+	return page.html, page.err
+}
@@ -0,0 +1,81 @@
+package gocco
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestGeneratorsInParallel(t *testing.T) {
+	if _, err := exec.LookPath("pygmentize"); err != nil {
+		t.Skip("no pygmentize")
+	}
+	src := []byte("// # Reading\n//\n// Reads a file.\npackage a\n\nfunc Read() {}\n")
+	linear := NewGenerator("a.go", "b.go")
+	linear.Layout = "linear"
+	parallel := NewGenerator("a.go", "c.go")
+	parallel.Markdown.HeadingOffset = 2
+	tests := []struct {
+		generator *Generator
+		want      []string
+		unwanted  []string
+	}{
+		{linear, []string{`class="linear"`, "b.html", "<h1 id=\"reading\""}, []string{"c.html", "<h3"}},
+		{parallel, []string{`class="parallel"`, "c.html", "<h3 id=\"reading\""}, []string{"b.html", "<h1 id"}},
+	}
+	var wg sync.WaitGroup
+	for _, test := range tests {
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func(g *Generator, want, unwanted []string) {
+				defer wg.Done()
+				html, err := g.Generate(src, Options{Filename: "a.go"})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				page := string(html)
+				for _, s := range want {
+					if !strings.Contains(page, s) {
+						t.Errorf("page of %q lacks %q", g.Sources, s)
+					}
+				}
+				for _, s := range unwanted {
+					if strings.Contains(page, s) {
+						t.Errorf("page of %q has %q of the other generator", g.Sources, s)
+					}
+				}
+			}(test.generator, test.want, test.unwanted)
+		}
+	}
+	wg.Wait()
+}
+
+// only the command's generator writes files
+func TestGenerateWritesNothing(t *testing.T) {
+	if _, err := exec.LookPath("pygmentize"); err != nil {
+		t.Skip("no pygmentize")
+	}
+	dir := makeTree(t, map[string]string{"logo.png": "png", "clip.mp4": "mp4"})
+	defer func(dir string, s, cards bool, base string) {
+		outputDir, search, socialCards, baseURL = dir, s, cards, base
+	}(outputDir, search, socialCards, baseURL)
+	outputDir = filepath.Join(dir, "docs")
+	search, socialCards, baseURL = true, true, "https://example.com"
+	src := []byte("// # Showing\n//\n// ![logo](logo.png)\n//\n// [clip](clip.mp4)\npackage a\n")
+	page, err := NewGenerator().Generate(src, Options{Filename: filepath.Join(dir, "a.go")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`<img src="logo.png"`, `<video controls preload="metadata" src="clip.mp4">`} {
+		if !strings.Contains(string(page), want) {
+			t.Errorf("page lacks %s", want)
+		}
+	}
+	if _, err := os.Stat(outputDir); !os.IsNotExist(err) {
+		t.Errorf("%s was written to", outputDir)
+	}
+}
@@ -0,0 +1,177 @@
+package gocco
+
+import (
+	"html"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ## Checking links
+
+// A book or a joined page is read by following its links: the jump
+// menu, the previous and next chapter, and references between chapters.
+// A link leading nowhere is easy to miss until a reader finds it, so in
+// these modes the pages are kept back until every one is rendered, and
+// each link to a page in `docs/` is checked for the page and the anchor
+// it points at. When one is broken, every broken link is reported and
+// gocco stops without writing any page. `-check-links=false` writes the
+// pages regardless
+var checkLinks = true
+
+// a `heldPage` is a rendered page waiting for its links to be checked
+type heldPage struct {
+	source string
+	html   []byte
+}
+
+var (
+	heldPages     = make(map[string]*heldPage)
+	heldPagesLock sync.Mutex
+)
+
+var linkPattern = regexp.MustCompile(`href="([^"]*)"`)
+
+// whether pages are held back for their links to be checked
+func holdingPages() bool {
+	return checkLinks && (book != nil || joinName != "")
+}
+
+// keep the page rendered for `source` until `releasePages`
+func holdPage(dest, source string, page []byte) {
+	heldPagesLock.Lock()
+	heldPages[filepath.Clean(dest)] = &heldPage{source, page}
+	heldPagesLock.Unlock()
+}
+
+// the pages written after the pages of the sources, which links may
+// point at without them existing yet
+func laterPages(packages []*APIPackage) map[string]bool {
+	later := make(map[string]bool)
+	add := func(page string) {
+		later[filepath.Join(outputDir, filepath.FromSlash(page))] = true
+	}
+	if joinName == "" && indexPage() != "" {
+		add(indexPage())
+	}
+	if search {
+		add("search.html")
+	}
+	if heatmap {
+		add("heatmap.html")
+	}
+	if stats {
+		add("stats.html")
+	}
+	if baseURL != "" {
+		add("404.html")
+	}
+	for _, p := range packages {
+		add(p.Page)
+	}
+	return later
+}
+
+// `releasePages` checks the links of the held pages, and writes them
+// when none is broken. It returns the number of broken links
+func releasePages(later map[string]bool) int {
+	heldPagesLock.Lock()
+	held := heldPages
+	heldPages = make(map[string]*heldPage)
+	heldPagesLock.Unlock()
+
+	anchors := make(map[string]map[string]bool)
+	broken := 0
+	var dests []string
+	for dest := range held {
+		dests = append(dests, dest)
+	}
+	sort.Strings(dests)
+	for _, dest := range dests {
+		for _, problem := range brokenLinks(dest, held, later, anchors) {
+			warn(held[dest].source, 0, problem)
+			broken++
+		}
+	}
+	if broken > 0 {
+		return broken
+	}
+	for _, dest := range dests {
+		if err := writeOutput(dest, held[dest].source, held[dest].html); err != nil {
+			renderFailure(err)
+		}
+	}
+	return 0
+}
+
+// the broken links of the held page `dest`. The ids of the pages linked
+// to are gathered into `anchors` as they're needed
+func brokenLinks(dest string, held map[string]*heldPage, later map[string]bool, anchors map[string]map[string]bool) []string {
+	var problems []string
+	reported := make(map[string]bool)
+	for _, m := range linkPattern.FindAllSubmatch(held[dest].html, -1) {
+		link := html.UnescapeString(string(m[1]))
+		path, fragment := link, ""
+		if i := strings.IndexByte(link, '#'); i >= 0 {
+			path, fragment = link[:i], link[i+1:]
+		}
+		// links to other sites, from the root of the site, and to files
+		// that aren't pages are left alone
+		if strings.Contains(path, ":") || strings.HasPrefix(path, "/") || path != "" && !strings.HasSuffix(path, ".html") {
+			continue
+		}
+		target := dest
+		if path != "" {
+			target = filepath.Join(filepath.Dir(dest), filepath.FromSlash(path))
+		}
+		rel, err := filepath.Rel(outputDir, target)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if reported[link] {
+			continue
+		}
+		ids, ok := anchors[target]
+		if !ok {
+			ids = pageAnchors(target, held)
+			anchors[target] = ids
+		}
+		switch {
+		case ids == nil && !later[target]:
+			problems = append(problems, "link to "+link+" leads to no page")
+		case ids != nil && fragment != "" && !ids[fragment]:
+			problems = append(problems, "link to "+link+" leads to no anchor on "+filepath.ToSlash(rel))
+		default:
+			continue
+		}
+		reported[link] = true
+	}
+	return problems
+}
+
+// the ids on the page `dest`, held or written by an earlier build, nil
+// when there is no such page
+func pageAnchors(dest string, held map[string]*heldPage) map[string]bool {
+	var page []byte
+	if h, ok := held[dest]; ok {
+		page = h.html
+	} else {
+		data, err := ioutil.ReadFile(dest)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				warn(dest, 0, err.Error())
+			}
+			return nil
+		}
+		page = data
+	}
+	ids := make(map[string]bool)
+	for _, m := range idPattern.FindAllSubmatch(page, -1) {
+		ids[string(m[1])] = true
+	}
+	return ids
+}
@@ -0,0 +1,79 @@
+package gocco
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBrokenLinks(t *testing.T) {
+	dir := makeTree(t, map[string]string{
+		"docs/old.html": `<h2 id="kept">Kept</h2>`,
+	})
+	defer func(dir string) { outputDir = dir }(outputDir)
+	outputDir = filepath.Join(dir, "docs")
+	page := func(name string) string { return filepath.Join(outputDir, filepath.FromSlash(name)) }
+	held := map[string]*heldPage{
+		page("b.html"):     {"b.go", []byte(`<h1 id="top">B</h1><p id="section-1"></p>`)},
+		page("sub/c.html"): {"sub/c.go", []byte(`<h1 id="top">C</h1>`)},
+	}
+	later := map[string]bool{page("index.html"): true}
+	tests := []struct {
+		name  string
+		links string
+		want  []string
+	}{
+		{"page", `<a href="b.html">`, nil},
+		{"anchor", `<a href="b.html#section-1">`, nil},
+		{"missing anchor", `<a href="b.html#section-2">`, []string{"link to b.html#section-2 leads to no anchor on b.html"}},
+		{"missing page", `<a href="d.html">`, []string{"link to d.html leads to no page"}},
+		{"page written later", `<a href="index.html">`, nil},
+		{"page of an earlier build", `<a href="old.html#kept">`, nil},
+		{"anchor of an earlier build", `<a href="old.html#gone">`, []string{"link to old.html#gone leads to no anchor on old.html"}},
+		{"subdirectory", `<a href="sub/c.html#top">`, nil},
+		{"same page", `<a href="#top"><a href="#nowhere">`, []string{"link to #nowhere leads to no anchor on a.html"}},
+		{"escaped", `<a href="b.html#section&#45;1">`, nil},
+		{"elsewhere", `<a href="https://example.com/x.html"><a href="/x.html"><a href="../x.html"><a href="logo.png">`, nil},
+		{"reported once", `<a href="d.html"><a href="d.html">`, []string{"link to d.html leads to no page"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pages := map[string]*heldPage{page("a.html"): {"a.go", []byte(`<h1 id="top">A</h1>` + test.links)}}
+			for dest, h := range held {
+				pages[dest] = h
+			}
+			got := brokenLinks(page("a.html"), pages, later, make(map[string]map[string]bool))
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("broken links %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReleasePages(t *testing.T) {
+	dir := makeTree(t, map[string]string{})
+	defer func(dir string) { outputDir = dir }(outputDir)
+	outputDir = filepath.Join(dir, "docs")
+	a, b := filepath.Join(outputDir, "a.html"), filepath.Join(outputDir, "b.html")
+
+	holdPage(a, "a.go", []byte(`<a href="b.html#missing">`))
+	holdPage(b, "b.go", []byte(`<h1 id="top">B</h1>`))
+	if broken := releasePages(nil); broken != 1 {
+		t.Errorf("%d broken links, want 1", broken)
+	}
+	if _, err := os.Stat(b); !os.IsNotExist(err) {
+		t.Error("pages were written despite a broken link")
+	}
+
+	holdPage(a, "a.go", []byte(`<a href="b.html#top">`))
+	holdPage(b, "b.go", []byte(`<h1 id="top">B</h1>`))
+	if broken := releasePages(nil); broken != 0 {
+		t.Errorf("%d broken links, want none", broken)
+	}
+	for _, page := range []string{a, b} {
+		if _, err := os.Stat(page); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package gocco
+
+import (
+	"container/list"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ## Links between sources
+
+// A comment may link to another source file, `see [the parser](parse.go)`,
+// which only makes sense on GitHub. When the target is documented in the
+// same run the link is pointed at its page instead
+
+var hrefPattern = regexp.MustCompile(`href="([^"#:?]+)([#?][^"]*)?"`)
+
+// `rewriteSourceLinks` points links to documented sources at their pages.
+// Targets are relative to the file each section comes from, which on a
+// joined page isn't `source`
+func (g *Generator) rewriteSourceLinks(source string, sections *list.List) {
+	documented := make(map[string]string)
+	for _, s := range g.Sources {
+		documented[filepath.Clean(s)] = s
+	}
+	for e := sections.Front(); e != nil; e = e.Next() {
+		section := e.Value.(*Section)
+		section.DocsHTML = hrefPattern.ReplaceAllFunc(section.DocsHTML, func(match []byte) []byte {
+			parts := hrefPattern.FindSubmatch(match)
+			target := string(parts[1])
+			if strings.HasPrefix(target, "/") {
+				return match
+			}
+			linked, ok := documented[filepath.Join(filepath.Dir(section.source), filepath.FromSlash(target))]
+			if !ok {
+				return match
+			}
+			link := pageLink(source, linked)
+			if !strings.Contains(link, "#") {
+				link += string(parts[2])
+			}
+			return []byte(`href="` + link + `"`)
+		})
+	}
+}
+
+// the address of the page of `to` relative to the page of `from`, or of
+// its heading when the files are joined into one page
+func pageLink(from, to string) string {
+	if joinName != "" {
+		return "#" + fileAnchor(to)
+	}
+	return relativeLink(destination(from), destination(to))
+}
@@ -0,0 +1,157 @@
+package gocco
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// ## Linting
+
+// `gocco lint` checks the prose of the doc comments, reporting every
+// problem as `file:line: message` so that editors and CI logs can point
+// straight at it. It exits with status 1 when anything was found
+
+// a `Problem` is something wrong with a line of docs
+type Problem struct {
+	Source  string
+	Line    int
+	Section int
+	Message string
+}
+
+func (p *Problem) String() string {
+	return fmt.Sprintf("%s:%d: section %d: %s", p.Source, p.Line, p.Section, p.Message)
+}
+
+// a `DocLine` is one line of a doc comment, with the comment delimiter
+// stripped
+type DocLine struct {
+	Number int
+	// The section the line ends up in, counted from 1 like the
+	// anchors of the page
+	Section int
+	Text    string
+}
+
+// `docLines` picks the prose out of a source: the text of every comment
+// line, leaving out code blocks written in the comments
+func docLines(source string, code []byte) []*DocLine {
+	language := getLanguage(source)
+	var lines []*DocLine
+	fenced := false
+	// sections are counted the way `parse` splits them, a comment after
+	// code starting a new one
+	section, hasCode := 1, false
+	for i, line := range splitLines(language, code) {
+		if !line.docs {
+			hasCode = true
+			continue
+		}
+		if hasCode {
+			section++
+			hasCode = false
+		}
+		text := string(line.text)
+		if strings.HasPrefix(strings.TrimSpace(text), "```") {
+			fenced = !fenced
+			continue
+		}
+		if fenced || strings.HasPrefix(text, "    ") || strings.HasPrefix(text, "\t") {
+			continue
+		}
+		lines = append(lines, &DocLine{i + 1, section, text})
+	}
+	return lines
+}
+
+// read a word list, one word per line, `#` starting a comment
+func readWords(file string) ([]string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var words []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			words = append(words, line)
+		}
+	}
+	return words, scanner.Err()
+}
+
+// check the spelling of every doc line of a source
+func lintSpelling(source string, lines []*DocLine) []*Problem {
+	var problems []*Problem
+	for _, line := range lines {
+		for _, word := range misspellings(line.Text) {
+			problems = append(problems, &Problem{source, line.Number, line.Section,
+				fmt.Sprintf("misspelled word %q", word)})
+		}
+	}
+	return problems
+}
+
+func lintCommand(args []string) {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	spell := flags.Bool("spell", false, "report misspelled words in doc comments")
+	wordsFile := flags.String("words", ".gocco-words", "file of extra words to accept, one per line")
+	prose := flags.Bool("prose", false, "check doc comments against the prose rules")
+	rulesFile := flags.String("rules", ".gocco-prose.json", "file with the prose rules")
+	annotationFormat := flags.String("annotations", "", "also print problems as CI annotations: github")
+	flags.Parse(args)
+	if err := setAnnotations(*annotationFormat); err != nil {
+		fail(exitConfig, err)
+	}
+
+	if !*spell && !*prose {
+		fail(exitConfig, "lint: nothing to check, use -spell or -prose")
+	}
+	if *spell {
+		words, err := readWords(*wordsFile)
+		if err != nil && !(os.IsNotExist(err) && *wordsFile == ".gocco-words") {
+			fail(exitConfig, err)
+		}
+		loadDictionary(words)
+	}
+	if *prose {
+		if err := loadProseRules(*rulesFile, *rulesFile != ".gocco-prose.json"); err != nil {
+			fail(exitConfig, err)
+		}
+	}
+
+	var problems []*Problem
+	for _, source := range collectSources(flags.Args()) {
+		if getLanguage(source) == nil {
+			warn(source, 0, "no language known")
+			continue
+		}
+		code, err := readSource(source)
+		if err != nil {
+			inputError(err)
+			continue
+		}
+		lines := docLines(source, code)
+		if *spell {
+			problems = append(problems, lintSpelling(source, lines)...)
+		}
+		if *prose {
+			problems = append(problems, lintProse(source, lines)...)
+		}
+	}
+	for _, problem := range problems {
+		fmt.Println(problem)
+		printAnnotation("error", problem.Source, problem.Line, problem.Message)
+	}
+	status := exitStatus()
+	if status == 0 && len(problems) > 0 {
+		status = exitProblems
+	}
+	os.Exit(status)
+}
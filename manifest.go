@@ -0,0 +1,98 @@
+package gocco
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ## The manifest
+
+// Everything gocco writes into `docs/` is listed in `docs/manifest.json`
+// with the file it was made from, its SHA-256 and its size, so tools
+// deploying or cleaning up the documentation know exactly which files
+// belong to it
+
+// a `ManifestEntry` describes one generated file. The path is relative
+// to `docs/`, the source empty for files gocco makes up itself
+type ManifestEntry struct {
+	Path   string `json:"path"`
+	Source string `json:"source,omitempty"`
+	SHA256 string `json:"sha256"`
+	Size   int    `json:"size"`
+}
+
+var (
+	manifest     = make(map[string]*ManifestEntry)
+	manifestLock sync.Mutex
+)
+
+func addToManifest(name, source string, data []byte) {
+	sum := sha256.Sum256(data)
+	recordManifest(name, source, sum[:], len(data))
+}
+
+// list a file by its SHA-256 and size, for files that were never whole
+// in memory
+func recordManifest(name, source string, sum []byte, size int) {
+	// files written outside of `docs/`, like `-export-sections`, aren't
+	// part of the docs
+	path, err := filepath.Rel(outputDir, name)
+	if err != nil || strings.HasPrefix(path, "..") {
+		return
+	}
+	manifestLock.Lock()
+	defer manifestLock.Unlock()
+	manifest[path] = &ManifestEntry{filepath.ToSlash(path), filepath.ToSlash(source), hex.EncodeToString(sum), size}
+}
+
+// the entries of the manifest sorted by path
+func manifestEntries() []*ManifestEntry {
+	manifestLock.Lock()
+	defer manifestLock.Unlock()
+	entries := make([]*ManifestEntry, 0, len(manifest))
+	for _, entry := range manifest {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries
+}
+
+func writeManifest() {
+	keepPreviousManifest()
+	saveManifest()
+}
+
+// write the manifest over the one there is
+func saveManifest() {
+	data, err := json.MarshalIndent(map[string]interface{}{"files": manifestEntries()}, "", "  ")
+	if err != nil {
+		renderFailure(err)
+		return
+	}
+	dest := filepath.Join(outputDir, "manifest.json")
+	if err := writeFile(dest, append(data, '\n')); err != nil {
+		renderFailure(err)
+	}
+}
+
+// With `-checksums` the files are also listed in `docs/SHA256SUMS`, in
+// the format of `sha256sum`, so a published copy of the documentation
+// can be checked with `sha256sum -c SHA256SUMS` from inside it
+var checksums bool
+
+func writeChecksums() {
+	sums := new(bytes.Buffer)
+	for _, entry := range manifestEntries() {
+		fmt.Fprintf(sums, "%s  %s\n", entry.SHA256, entry.Path)
+	}
+	if err := writeFile(filepath.Join(outputDir, "SHA256SUMS"), sums.Bytes()); err != nil {
+		renderFailure(err)
+	}
+}
@@ -0,0 +1,111 @@
+package gocco
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// run `f` with a manifest of its own, writing into `docs/` of a new tree
+func withManifest(t *testing.T, f func(dir string)) {
+	t.Helper()
+	dir := makeTree(t, map[string]string{})
+	defer func(dir string, m map[string]*ManifestEntry) { outputDir, manifest = dir, m }(outputDir, manifest)
+	outputDir = filepath.Join(dir, "docs")
+	manifest = make(map[string]*ManifestEntry)
+	f(dir)
+}
+
+func sum(data string) string {
+	s := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(s[:])
+}
+
+func TestManifest(t *testing.T) {
+	withManifest(t, func(dir string) {
+		writes := []struct {
+			name, source, data string
+			stream             bool
+		}{
+			{"docs/a.html", "a.go", "<p>a</p>", false},
+			{"docs/sub/b.html", "sub/b.go", "<p>b</p>", true},
+			{"docs/gocco.css", "", "body {}", false},
+			{"docs/a.html", "a.go", "<p>a again</p>", false},
+			{"sections.json", "", "[]", false},
+		}
+		for _, w := range writes {
+			name := filepath.Join(dir, filepath.FromSlash(w.name))
+			var err error
+			if w.stream {
+				err = writeStream(name, w.source, func(out io.Writer) error {
+					_, err := io.WriteString(out, w.data)
+					return err
+				})
+			} else {
+				err = writeOutput(name, w.source, []byte(w.data))
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		want := []*ManifestEntry{
+			{"a.html", "a.go", sum("<p>a again</p>"), len("<p>a again</p>")},
+			{"gocco.css", "", sum("body {}"), len("body {}")},
+			{"sub/b.html", "sub/b.go", sum("<p>b</p>"), len("<p>b</p>")},
+		}
+		if got := manifestEntries(); !reflect.DeepEqual(got, want) {
+			t.Errorf("manifest %+v, want %+v", got, want)
+		}
+		writeManifest()
+		read, err := readManifest(filepath.Join(outputDir, "manifest.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range want {
+			if !reflect.DeepEqual(read[entry.Path], entry) {
+				t.Errorf("manifest.json lists %+v, want %+v", read[entry.Path], entry)
+			}
+		}
+	})
+}
+
+func TestChecksums(t *testing.T) {
+	withManifest(t, func(dir string) {
+		for name, data := range map[string]string{"a.html": "<p>a</p>", "sub/b.html": "<p>b</p>"} {
+			if err := writeOutput(filepath.Join(outputDir, filepath.FromSlash(name)), "", []byte(data)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		writeManifest()
+		writeChecksums()
+		data, err := ioutil.ReadFile(filepath.Join(outputDir, "SHA256SUMS"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		var paths []string
+		// what `sha256sum -c` checks
+		for _, line := range lines {
+			fields := strings.SplitN(line, "  ", 2)
+			if len(fields) != 2 {
+				t.Fatalf("line %q isn't in the format of sha256sum", line)
+			}
+			contents, err := ioutil.ReadFile(filepath.Join(outputDir, filepath.FromSlash(fields[1])))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sum(string(contents)) != fields[0] {
+				t.Errorf("%s doesn't match its checksum", fields[1])
+			}
+			paths = append(paths, fields[1])
+		}
+		if want := []string{"a.html", "manifest.json", "sub/b.html"}; !reflect.DeepEqual(paths, want) {
+			t.Errorf("checksums of %q, want %q", paths, want)
+		}
+	})
+}
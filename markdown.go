@@ -0,0 +1,312 @@
+package gocco
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/russross/blackfriday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+)
+
+// ## Checking markdown
+
+// Markdown never fails, it just renders something other than what was
+// meant. A few slips are common enough in comments to point out while
+// building, at the line they're on:
+//
+//   - a code fence that is never closed, which turns the rest of the
+//     comment into code
+//   - emphasis markers without a partner, which show up as stray `*`
+//     and `_`
+//   - an HTML block, like `<div>`, right after a line of text. Without
+//     a blank line before it the block becomes part of the paragraph,
+//     wrapped in a `<p>` it isn't allowed in, which browsers take apart
+var (
+	fencePattern     = regexp.MustCompile("^\\s*(```|~~~)")
+	codeSpanPattern  = regexp.MustCompile("`+[^`]*`+")
+	escapedPattern   = regexp.MustCompile(`\\.`)
+	bulletPattern    = regexp.MustCompile(`^\s*[*+-]\s`)
+	underscoreInWord = regexp.MustCompile(`\w_+\w`)
+	blockHTMLPattern = regexp.MustCompile(`^\s*<(address|article|aside|blockquote|details|div|dl|fieldset|figure|footer|form|h[1-6]|header|hr|ol|p|pre|section|table|ul)\b`)
+)
+
+// `checkMarkdown` warns about the slips in the docs of every section
+func checkMarkdown(sections *list.List) {
+	for e := sections.Front(); e != nil; e = e.Next() {
+		section := e.Value.(*Section)
+		for _, problem := range markdownProblems(string(section.docsText)) {
+			warn(section.source, section.line+problem.Line, problem.Message)
+		}
+	}
+}
+
+// the slips in a piece of markdown, at their line counted from 0
+func markdownProblems(docs string) []*Problem {
+	var problems []*Problem
+	add := func(line int, format string, args ...interface{}) {
+		problems = append(problems, &Problem{Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+	lines := strings.Split(docs, "\n")
+	fence, fenceLine := "", 0
+	paragraph, paragraphLine := "", 0
+	// check the emphasis of a paragraph once it ends
+	endParagraph := func() {
+		text := escapedPattern.ReplaceAllString(codeSpanPattern.ReplaceAllString(paragraph, ""), "")
+		if strings.Count(text, "**")%2 != 0 {
+			add(paragraphLine, "unbalanced ** in paragraph")
+		}
+		text = strings.Replace(text, "**", "", -1)
+		if strings.Count(text, "*")%2 != 0 {
+			add(paragraphLine, "unbalanced * in paragraph")
+		}
+		text = underscoreInWord.ReplaceAllString(text, "")
+		if strings.Count(text, "__")%2 != 0 || strings.Count(strings.Replace(text, "__", "", -1), "_")%2 != 0 {
+			add(paragraphLine, "unbalanced _ in paragraph")
+		}
+		paragraph = ""
+	}
+	for i, line := range lines {
+		if m := fencePattern.FindStringSubmatch(line); m != nil {
+			switch {
+			case fence == "":
+				endParagraph()
+				fence, fenceLine = m[1], i
+			case m[1] == fence:
+				fence = ""
+			}
+			continue
+		}
+		if fence != "" || strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			endParagraph()
+			continue
+		}
+		if paragraph != "" && blockHTMLPattern.MatchString(line) {
+			add(i, "HTML block right after text, add a blank line before it")
+		}
+		if paragraph == "" {
+			paragraphLine = i
+		}
+		paragraph += bulletPattern.ReplaceAllString(line, "") + "\n"
+	}
+	endParagraph()
+	if fence != "" {
+		add(fenceLine, "code fence %s is never closed", fence)
+	}
+	return problems
+}
+
+// ## Rendering markdown
+
+// How the docs are rendered can be tuned in the `markdown` part of the
+// configuration. The defaults are those of blackfriday's
+// `MarkdownCommon`, with headings getting GitHub's anchors
+type MarkdownOptions struct {
+	// `blackfriday` renders the docs with blackfriday, `goldmark` with
+	// goldmark, see `goldmarkMarkdown`
+	Engine string `json:"engine"`
+	// Turn every line break in a comment into a `<br>`, rather than
+	// joining the lines of a paragraph
+	HardWraps bool `json:"hard_wraps"`
+	// `keep` passes HTML in comments through, `skip` drops it
+	RawHTML string `json:"raw_html"`
+	// `github` makes heading anchors the way GitHub does, `blackfriday`
+	// the way blackfriday does
+	HeadingIDs string `json:"heading_ids"`
+	// `same` opens links in the same tab, `blank` opens links to other
+	// sites in a new one
+	LinkTarget string `json:"link_target"`
+	// Pass fences marked ```` ```html raw ```` through untouched, see
+	// `rawBlocks`
+	RawBlocks bool `json:"raw_blocks"`
+	// Demote the headings of the docs by this many levels, see
+	// `demoteHeadings`. A page's front matter can set its own with
+	// `heading_offset`
+	HeadingOffset int `json:"heading_offset"`
+}
+
+var defaultMarkdown = MarkdownOptions{
+	Engine:     "blackfriday",
+	RawHTML:    "keep",
+	HeadingIDs: "github",
+	LinkTarget: "same",
+}
+
+func (o *MarkdownOptions) check() error {
+	switch {
+	case o.Engine != "blackfriday" && o.Engine != "goldmark":
+		return fmt.Errorf("engine is %q, use blackfriday or goldmark", o.Engine)
+	case o.RawHTML != "keep" && o.RawHTML != "skip":
+		return fmt.Errorf("raw_html is %q, use keep or skip", o.RawHTML)
+	case o.HeadingIDs != "github" && o.HeadingIDs != "blackfriday":
+		return fmt.Errorf("heading_ids is %q, use github or blackfriday", o.HeadingIDs)
+	case o.LinkTarget != "same" && o.LinkTarget != "blank":
+		return fmt.Errorf("link_target is %q, use same or blank", o.LinkTarget)
+	case o.HeadingOffset < 0 || o.HeadingOffset > 5:
+		return fmt.Errorf("heading_offset is %d, use 0 to 5", o.HeadingOffset)
+	}
+	return nil
+}
+
+// `markdown` renders text with the configured engine and options
+func (g *Generator) markdown(text []byte) []byte {
+	return g.markdownOffset(text, 0)
+}
+
+// `markdownOffset` renders text like `markdown`, with its headings
+// demoted by `offset` levels
+func (g *Generator) markdownOffset(text []byte, offset int) []byte {
+	options := g.Markdown
+	var raw [][]byte
+	if options.RawBlocks {
+		text, raw = rawBlocks(text)
+	}
+	var rendered []byte
+	if options.Engine == "goldmark" {
+		rendered = g.goldmarkMarkdown(text)
+	} else {
+		rendered = g.blackfridayMarkdown(text)
+	}
+	// before the raw blocks are put back, which are left as written
+	rendered = demoteHeadings(rendered, offset)
+	for i, block := range raw {
+		rendered = bytes.Replace(rendered, []byte("<p>"+rawPlaceholder(i)+"</p>"), block, 1)
+	}
+	// neither engine can tell `other.html` from a link to another site,
+	// so the targets are added here
+	if options.LinkTarget == "blank" {
+		rendered = externalLinkPattern.ReplaceAll(rendered, []byte(`<a target="_blank" rel="noopener" href="$1"`))
+	}
+	return rendered
+}
+
+func (g *Generator) blackfridayMarkdown(text []byte) []byte {
+	options := g.Markdown
+	flags := blackfriday.HTML_USE_XHTML |
+		blackfriday.HTML_USE_SMARTYPANTS |
+		blackfriday.HTML_SMARTYPANTS_FRACTIONS |
+		blackfriday.HTML_SMARTYPANTS_DASHES |
+		blackfriday.HTML_SMARTYPANTS_LATEX_DASHES
+	extensions := blackfriday.EXTENSION_NO_INTRA_EMPHASIS |
+		blackfriday.EXTENSION_TABLES |
+		blackfriday.EXTENSION_FENCED_CODE |
+		blackfriday.EXTENSION_AUTOLINK |
+		blackfriday.EXTENSION_STRIKETHROUGH |
+		blackfriday.EXTENSION_SPACE_HEADERS |
+		blackfriday.EXTENSION_HEADER_IDS |
+		blackfriday.EXTENSION_BACKSLASH_LINE_BREAK |
+		blackfriday.EXTENSION_DEFINITION_LISTS
+	if options.HardWraps {
+		extensions |= blackfriday.EXTENSION_HARD_LINE_BREAK
+	}
+	if options.RawHTML == "skip" {
+		flags |= blackfriday.HTML_SKIP_HTML
+	}
+	// headings without an id get one from `anchorHeadings` later
+	if options.HeadingIDs == "blackfriday" {
+		extensions |= blackfriday.EXTENSION_AUTO_HEADER_IDS
+	}
+	renderer := blackfriday.HtmlRenderer(flags, "", "")
+	return blackfriday.MarkdownOptions(text, renderer, blackfriday.Options{Extensions: extensions})
+}
+
+// ## goldmark
+
+// blackfriday predates CommonMark, and has neither footnotes nor task
+// lists. With `"engine": "goldmark"` the docs are rendered by
+// [goldmark](https://github.com/yuin/goldmark) instead, following
+// CommonMark with GitHub's tables, strikethrough, autolinks and task
+// lists, plus footnotes, definition lists and smart punctuation. Headings
+// take an id written after them, `# Heading {#id}`, as with blackfriday
+func (g *Generator) goldmarkMarkdown(text []byte) []byte {
+	options := g.Markdown
+	parserOptions := []parser.Option{parser.WithAttribute()}
+	if options.HeadingIDs == "blackfriday" {
+		parserOptions = append(parserOptions, parser.WithAutoHeadingID())
+	}
+	rendererOptions := []renderer.Option{goldmarkhtml.WithXHTML()}
+	if options.HardWraps {
+		rendererOptions = append(rendererOptions, goldmarkhtml.WithHardWraps())
+	}
+	// goldmark drops HTML unless told not to, the opposite of blackfriday
+	if options.RawHTML == "keep" {
+		rendererOptions = append(rendererOptions, goldmarkhtml.WithUnsafe())
+	}
+	md := goldmark.New(
+		goldmark.WithExtensions(extension.GFM, extension.Footnote, extension.DefinitionList, extension.Typographer),
+		goldmark.WithParserOptions(parserOptions...),
+		goldmark.WithRendererOptions(rendererOptions...),
+	)
+	buf := new(bytes.Buffer)
+	if err := md.Convert(text, buf); err != nil {
+		// goldmark only fails writing to the buffer, which doesn't
+		return text
+	}
+	return buf.Bytes()
+}
+
+var externalLinkPattern = regexp.MustCompile(`<a href="((?:[a-z][a-z0-9+.-]*:)?//[^"]*)"`)
+
+// ## Raw HTML blocks
+
+// Some things markdown can't express, like a table with merged cells or
+// a widget of the project's own. With `"raw_blocks": true` in the
+// `markdown` part of the configuration, a fence marked `html raw` goes
+// into the page as it is written:
+//
+//     ```html raw
+//     <table class="matrix">...</table>
+//     ```
+//
+// Nothing in it is rendered, smartened or dropped, even when `raw_html`
+// is `skip`, so only turn it on for docs whose authors are trusted.
+// Without the setting the fence is shown as HTML code
+var rawFencePattern = regexp.MustCompile("^ {0,3}(```|~~~)\\s*html\\s+raw\\s*$")
+
+// `rawBlocks` takes the raw fences out of `text`, leaving a paragraph
+// holding a placeholder in the place of each, and returns the blocks
+func rawBlocks(text []byte) ([]byte, [][]byte) {
+	lines := bytes.SplitAfter(text, []byte("\n"))
+	var out []byte
+	var blocks [][]byte
+	for i := 0; i < len(lines); i++ {
+		m := rawFencePattern.FindSubmatch(bytes.TrimRight(lines[i], "\r\n"))
+		if m == nil {
+			out = append(out, lines[i]...)
+			continue
+		}
+		var block []byte
+		j := i + 1
+		for ; j < len(lines); j++ {
+			if bytes.HasPrefix(bytes.TrimSpace(lines[j]), m[1]) && len(bytes.TrimSpace(lines[j])) == len(m[1]) {
+				break
+			}
+			block = append(block, lines[j]...)
+		}
+		// a fence that is never closed is left to markdown, which
+		// `checkMarkdown` warns about
+		if j == len(lines) {
+			out = append(out, lines[i]...)
+			continue
+		}
+		out = append(out, "\n"+rawPlaceholder(len(blocks))+"\n\n"...)
+		blocks = append(blocks, block)
+		i = j
+	}
+	return out, blocks
+}
+
+// the paragraph standing in for a raw block while the markdown around it
+// is rendered
+func rawPlaceholder(i int) string {
+	return fmt.Sprintf("GOCCORAWBLOCK%dX", i)
+}
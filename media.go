@@ -0,0 +1,74 @@
+package gocco
+
+import (
+	"container/list"
+	"fmt"
+	"html"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ## Recordings and videos
+
+// A terminal session says more about a command line tool than a page of
+// prose. A link standing alone in its paragraph, to an asciinema
+// recording, a YouTube video or a video file, is turned into a player.
+// Every player keeps the link as a fallback for readers without scripts
+// or frames
+var mediaLinkPattern = regexp.MustCompile(`<p>\s*<a href="([^"]+)"[^>]*>([^<]*)</a>\s*</p>`)
+
+var (
+	asciinemaPattern = regexp.MustCompile(`^https?://asciinema\.org/a/(\w+)/?$`)
+	youtubePattern   = regexp.MustCompile(`^https?://(?:www\.)?(?:youtube\.com/watch\?v=|youtu\.be/)([\w-]+)`)
+	videoExtensions  = map[string]bool{".mp4": true, ".webm": true, ".ogv": true, ".mov": true}
+)
+
+// `embedMedia` replaces the links to recordings and videos in the docs
+// of a page with players. Local videos are copied next to the pages
+// when `copyVideos` is set
+func embedMedia(source string, sections *list.List, copyVideos bool) {
+	for e := sections.Front(); e != nil; e = e.Next() {
+		section := e.Value.(*Section)
+		section.DocsHTML = mediaLinkPattern.ReplaceAllFunc(section.DocsHTML, func(match []byte) []byte {
+			parts := mediaLinkPattern.FindSubmatch(match)
+			href := html.UnescapeString(string(parts[1]))
+			fallback := string(match[len("<p>") : len(match)-len("</p>")])
+			if player := mediaPlayer(source, section.source, href, strings.TrimSpace(fallback), copyVideos); player != "" {
+				return []byte(player)
+			}
+			return match
+		})
+	}
+}
+
+// the player for `href`, empty if it isn't a recording or video
+func mediaPlayer(page, source, href, fallback string, copyVideos bool) string {
+	if m := asciinemaPattern.FindStringSubmatch(href); m != nil {
+		return fmt.Sprintf(`<div class="media"><script id="asciicast-%s" src="https://asciinema.org/a/%s.js" async></script>`+
+			`<noscript>%s</noscript></div>`, m[1], m[1], fallback)
+	}
+	if m := youtubePattern.FindStringSubmatch(href); m != nil {
+		return fmt.Sprintf(`<div class="media"><iframe src="https://www.youtube-nocookie.com/embed/%s" title="%s" `+
+			`allowfullscreen loading="lazy">%s</iframe></div>`, m[1], html.EscapeString(href), fallback)
+	}
+	u, err := url.Parse(href)
+	if err != nil || !videoExtensions[strings.ToLower(path.Ext(u.Path))] {
+		return ""
+	}
+	// local videos are copied next to the pages like images
+	if copyVideos && u.Scheme == "" && u.Host == "" && !strings.HasPrefix(u.Path, "/") {
+		asset, err := copyAsset(filepath.Join(filepath.Dir(source), filepath.FromSlash(u.Path)))
+		if err != nil {
+			warn(source, 0, "missing video "+href)
+			return ""
+		}
+		link := assetLink(page, asset)
+		fallback = strings.Replace(fallback, `href="`+html.EscapeString(href)+`"`, `href="`+html.EscapeString(link)+`"`, 1)
+		href = link
+	}
+	return fmt.Sprintf(`<div class="media"><video controls preload="metadata" src="%s">%s</video></div>`,
+		html.EscapeString(href), fallback)
+}
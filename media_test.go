@@ -0,0 +1,62 @@
+package gocco
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEmbedMedia(t *testing.T) {
+	dir := makeTree(t, map[string]string{"clip.mp4": "mp4"})
+	defer func(dir string) { outputDir = dir }(outputDir)
+	outputDir = filepath.Join(dir, "docs")
+	source := filepath.Join(dir, "a.go")
+	sum := sha256.Sum256([]byte("mp4"))
+	copied := "assets/clip-" + hex.EncodeToString(sum[:4]) + ".mp4"
+	// the page of a source outside the working directory is a level down
+	linked := "../" + copied
+	link := func(href string) string { return `<a href="` + href + `">` + href + `</a>` }
+	tests := []struct {
+		name       string
+		docs       string
+		copyVideos bool
+		want       string
+	}{
+		{"asciinema", "<p>" + link("https://asciinema.org/a/113463") + "</p>", true,
+			`<div class="media"><script id="asciicast-113463" src="https://asciinema.org/a/113463.js" async></script>` +
+				`<noscript>` + link("https://asciinema.org/a/113463") + `</noscript></div>`},
+		{"youtube", "<p>" + link("https://youtu.be/dQw4w9WgXcQ") + "</p>", true,
+			`<div class="media"><iframe src="https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ" title="https://youtu.be/dQw4w9WgXcQ" ` +
+				`allowfullscreen loading="lazy">` + link("https://youtu.be/dQw4w9WgXcQ") + `</iframe></div>`},
+		{"youtube watch", "<p>" + link("https://www.youtube.com/watch?v=abc-1") + "</p>", true,
+			`<div class="media"><iframe src="https://www.youtube-nocookie.com/embed/abc-1" title="https://www.youtube.com/watch?v=abc-1" ` +
+				`allowfullscreen loading="lazy">` + link("https://www.youtube.com/watch?v=abc-1") + `</iframe></div>`},
+		{"remote video", "<p>" + link("https://example.com/clip.webm") + "</p>", true,
+			`<div class="media"><video controls preload="metadata" src="https://example.com/clip.webm">` +
+				link("https://example.com/clip.webm") + `</video></div>`},
+		{"local video", "<p>" + link("clip.mp4") + "</p>", true,
+			`<div class="media"><video controls preload="metadata" src="` + linked + `">` +
+				`<a href="` + linked + `">clip.mp4</a></video></div>`},
+		{"local video left in place", "<p>" + link("clip.mp4") + "</p>", false,
+			`<div class="media"><video controls preload="metadata" src="clip.mp4">` + link("clip.mp4") + `</video></div>`},
+		{"missing video", "<p>" + link("gone.mp4") + "</p>", true, "<p>" + link("gone.mp4") + "</p>"},
+		{"other link", "<p>" + link("https://example.com/") + "</p>", true, "<p>" + link("https://example.com/") + "</p>"},
+		{"link in a sentence", "<p>See " + link("https://youtu.be/x") + ".</p>", true, "<p>See " + link("https://youtu.be/x") + ".</p>"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sections := list.New()
+			sections.PushBack(&Section{DocsHTML: []byte(test.docs), source: source})
+			embedMedia(source, sections, test.copyVideos)
+			if got := string(sections.Front().Value.(*Section).DocsHTML); got != test.want {
+				t.Errorf("embedded\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, filepath.FromSlash(copied))); err != nil {
+		t.Error(err)
+	}
+}
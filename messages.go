@@ -0,0 +1,215 @@
+package gocco
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ## Messages
+
+// The words gocco itself puts on pages come from a catalog, picked with
+// `-language`, so that docs written in another language don't carry
+// English labels. `-messages` reads a JSON file of the same shape to add
+// a language, or to reword one
+var catalogs = map[string]map[string]string{
+	"en": {
+		"generated":      "Generated {time}",
+		"jump_to":        "Jump To …",
+		"index":          "Index",
+		"files":          "Files",
+		"api":            "API",
+		"constants":      "Constants",
+		"variables":      "Variables",
+		"functions":      "Functions",
+		"types":          "Types",
+		"search":         "Search",
+		"raw":            "Raw source",
+		"no_results":     "Nothing found for “{query}”.",
+		"not_found":      "Page not found",
+		"not_found_text": "There is no page at this address. These are the documented files:",
+		"copy":           "copy",
+		"copied":         "copied",
+		"collapse":       "collapse",
+		"expand":         "expand",
+		"show_lines":     "show {lines} lines",
+		"resize":         "drag to resize",
+		"find":           "find in code",
+		"matches":        "{current} of {total}",
+	},
+	"de": {
+		"generated":      "Erstellt am {time}",
+		"jump_to":        "Springe zu …",
+		"index":          "Übersicht",
+		"files":          "Dateien",
+		"api":            "API",
+		"constants":      "Konstanten",
+		"variables":      "Variablen",
+		"functions":      "Funktionen",
+		"types":          "Typen",
+		"search":         "Suche",
+		"raw":            "Quelltext",
+		"no_results":     "Keine Treffer für „{query}“.",
+		"not_found":      "Seite nicht gefunden",
+		"not_found_text": "Unter dieser Adresse gibt es keine Seite. Dies sind die dokumentierten Dateien:",
+		"copy":           "kopieren",
+		"copied":         "kopiert",
+		"collapse":       "einklappen",
+		"expand":         "ausklappen",
+		"show_lines":     "{lines} Zeilen zeigen",
+		"resize":         "ziehen, um die Breite zu ändern",
+		"find":           "im Code suchen",
+		"matches":        "{current} von {total}",
+	},
+	"fr": {
+		"generated":      "Généré le {time}",
+		"jump_to":        "Aller à …",
+		"index":          "Sommaire",
+		"files":          "Fichiers",
+		"api":            "API",
+		"constants":      "Constantes",
+		"variables":      "Variables",
+		"functions":      "Fonctions",
+		"types":          "Types",
+		"search":         "Rechercher",
+		"raw":            "Source brute",
+		"no_results":     "Aucun résultat pour « {query} ».",
+		"not_found":      "Page introuvable",
+		"not_found_text": "Il n’y a pas de page à cette adresse. Voici les fichiers documentés :",
+		"copy":           "copier",
+		"copied":         "copié",
+		"collapse":       "replier",
+		"expand":         "déplier",
+		"show_lines":     "afficher {lines} lignes",
+		"resize":         "faire glisser pour redimensionner",
+		"find":           "chercher dans le code",
+		"matches":        "{current} sur {total}",
+	},
+	"es": {
+		"generated":      "Generado el {time}",
+		"jump_to":        "Ir a …",
+		"index":          "Índice",
+		"files":          "Archivos",
+		"api":            "API",
+		"constants":      "Constantes",
+		"variables":      "Variables",
+		"functions":      "Funciones",
+		"types":          "Tipos",
+		"search":         "Buscar",
+		"raw":            "Código fuente",
+		"no_results":     "No se encontró nada para «{query}».",
+		"not_found":      "Página no encontrada",
+		"not_found_text": "No hay ninguna página en esta dirección. Estos son los archivos documentados:",
+		"copy":           "copiar",
+		"copied":         "copiado",
+		"collapse":       "contraer",
+		"expand":         "expandir",
+		"show_lines":     "mostrar {lines} líneas",
+		"resize":         "arrastrar para redimensionar",
+		"find":           "buscar en el código",
+		"matches":        "{current} de {total}",
+	},
+}
+
+// the language of the pages, set with `-language`
+var uiLanguage = "en"
+
+// add the catalogs of a `-messages` file, keyed by language
+func loadMessages(file string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var extra map[string]map[string]string
+	if err := json.Unmarshal(data, &extra); err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	for language, messages := range extra {
+		if catalogs[language] == nil {
+			catalogs[language] = make(map[string]string)
+		}
+		for key, message := range messages {
+			catalogs[language][key] = expandEnv(file, message)
+		}
+	}
+	return nil
+}
+
+// check `-language` names a known catalog
+func setLanguage(language string) error {
+	if catalogs[language] == nil {
+		var known []string
+		for name := range catalogs {
+			known = append(known, name)
+		}
+		sort.Strings(known)
+		return fmt.Errorf("no messages for language %q, known are %s", language, strings.Join(known, ", "))
+	}
+	uiLanguage = language
+	return nil
+}
+
+// look up a message, falling back to English for anything a catalog
+// doesn't translate
+func message(key string) string {
+	if m, ok := catalogs[uiLanguage][key]; ok {
+		return m
+	}
+	return catalogs["en"][key]
+}
+
+// the messages scripts need, as JSON for a `<script type="application/json">`
+// block which, not being executed, is allowed even with `-csp`
+func messagesJSON() string {
+	messages := make(map[string]string)
+	for key := range catalogs["en"] {
+		messages[key] = message(key)
+	}
+	data, _ := json.Marshal(messages)
+	return string(data)
+}
+
+// ### Direction
+
+// the writing direction of the pages, `ltr`, `rtl` or `auto` to decide
+// per page, set with `-direction`
+var direction = "auto"
+
+func setDirection(value string) error {
+	switch value {
+	case "ltr", "rtl", "auto":
+		direction = value
+		return nil
+	}
+	return fmt.Errorf("unknown direction %q, use ltr, rtl or auto", value)
+}
+
+// `pageDirection` decides which way a page reads. In `auto` mode a page
+// is right-to-left when most letters of its docs belong to scripts
+// written that way, like Arabic or Hebrew
+func pageDirection(sections *list.List) string {
+	if direction != "auto" {
+		return direction
+	}
+	var rtl, ltr int
+	for e := sections.Front(); e != nil; e = e.Next() {
+		for _, r := range string(e.Value.(*Section).docsText) {
+			if !unicode.IsLetter(r) {
+				continue
+			}
+			if unicode.In(r, unicode.Arabic, unicode.Hebrew, unicode.Syriac, unicode.Thaana, unicode.Nko) {
+				rtl++
+			} else {
+				ltr++
+			}
+		}
+	}
+	if rtl > ltr {
+		return "rtl"
+	}
+	return "ltr"
+}
@@ -0,0 +1,94 @@
+package gocco
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ## Metrics
+
+// A hosted renderer wants watching. `gocco serve` answers `/metrics` in
+// the text format Prometheus scrapes, with
+//
+//   - `gocco_render_requests_total`, page requests by status
+//   - `gocco_render_duration_seconds`, a histogram of how long they took
+//   - `gocco_render_cache_hits_total` and `gocco_render_cache_misses_total`
+
+// the upper bounds of the duration histogram's buckets, in seconds
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type renderMetrics struct {
+	sync.Mutex
+	requests    map[string]int
+	buckets     []int
+	durationSum float64
+	durations   int
+	hits        int
+	misses      int
+}
+
+var metrics = &renderMetrics{requests: make(map[string]int), buckets: make([]int, len(durationBuckets))}
+
+// count a page request that ended with `status`
+func (m *renderMetrics) request(status string, duration time.Duration) {
+	m.Lock()
+	defer m.Unlock()
+	m.requests[status]++
+	seconds := duration.Seconds()
+	for i, bound := range durationBuckets {
+		if seconds <= bound {
+			m.buckets[i]++
+		}
+	}
+	m.durationSum += seconds
+	m.durations++
+}
+
+func (m *renderMetrics) cacheHit() {
+	m.Lock()
+	m.hits++
+	m.Unlock()
+}
+
+func (m *renderMetrics) cacheMiss() {
+	m.Lock()
+	m.misses++
+	m.Unlock()
+}
+
+func serveMetrics(w http.ResponseWriter, r *http.Request) {
+	m := metrics
+	m.Lock()
+	defer m.Unlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	fmt.Fprintln(w, "# HELP gocco_render_requests_total Page requests by status.")
+	fmt.Fprintln(w, "# TYPE gocco_render_requests_total counter")
+	var statuses []string
+	for status := range m.requests {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	for _, status := range statuses {
+		fmt.Fprintf(w, "gocco_render_requests_total{status=%q} %d\n", status, m.requests[status])
+	}
+
+	fmt.Fprintln(w, "# HELP gocco_render_duration_seconds Time taken to answer page requests.")
+	fmt.Fprintln(w, "# TYPE gocco_render_duration_seconds histogram")
+	for i, bound := range durationBuckets {
+		fmt.Fprintf(w, "gocco_render_duration_seconds_bucket{le=\"%g\"} %d\n", bound, m.buckets[i])
+	}
+	fmt.Fprintf(w, "gocco_render_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.durations)
+	fmt.Fprintf(w, "gocco_render_duration_seconds_sum %g\n", m.durationSum)
+	fmt.Fprintf(w, "gocco_render_duration_seconds_count %d\n", m.durations)
+
+	fmt.Fprintln(w, "# HELP gocco_render_cache_hits_total Pages served from the cache.")
+	fmt.Fprintln(w, "# TYPE gocco_render_cache_hits_total counter")
+	fmt.Fprintf(w, "gocco_render_cache_hits_total %d\n", m.hits)
+	fmt.Fprintln(w, "# HELP gocco_render_cache_misses_total Pages rendered because they weren't cached or had changed.")
+	fmt.Fprintln(w, "# TYPE gocco_render_cache_misses_total counter")
+	fmt.Fprintf(w, "gocco_render_cache_misses_total %d\n", m.misses)
+}
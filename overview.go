@@ -0,0 +1,148 @@
+package gocco
+
+import (
+	"container/list"
+	"go/parser"
+	"go/token"
+	"log"
+	"path/filepath"
+	"strings"
+)
+
+// ## Package overviews
+
+// By convention the documentation of a Go package as a whole lives in
+// the package comment of its `doc.go`. Like godoc, gocco gives it a page
+// of its own: the comment across the full width, followed by links to
+// the package's other files
+func isPackageDoc(source string) bool {
+	return filepath.Base(source) == "doc.go" && joinName == "" && outputFormat != "json"
+}
+
+func (g *Generator) generateOverview(source string) (write func()) {
+	failed := func(err error) func() {
+		return func() { inputError(err) }
+	}
+	// sources from archives and addresses only exist in memory
+	code, err := readSource(source)
+	if err != nil {
+		return failed(err)
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), source, code, parser.ParseComments|parser.PackageClauseOnly)
+	if err != nil {
+		return failed(err)
+	}
+	var files []string
+	for _, s := range g.Sources {
+		if s != source && filepath.Dir(s) == filepath.Dir(source) {
+			files = append(files, s)
+		}
+	}
+	dest := destination(source)
+	html, err := g.renderOverview(dest, source, file.Name.Name, file.Doc.Text(), "", listedSources(files))
+	if err != nil {
+		return failed(err)
+	}
+	return func() {
+		log.Println("gocco: ", source, " -> ", dest)
+		if err := writeOutput(dest, source, html); err != nil {
+			inputError(err)
+		}
+	}
+}
+
+// render the overview page at `dest` of the package in the directory of
+// `source`
+func (g *Generator) renderOverview(dest, source, name, text, readme string, files []string) ([]byte, error) {
+	links := make([]*JumpLink, len(files))
+	for i, s := range files {
+		links[i] = &JumpLink{filepath.Base(s), relativeLink(dest, destination(s)), ""}
+	}
+	api := ""
+	if page := apiPageOf[filepath.Dir(source)]; page != "" {
+		api = relativeLink(dest, filepath.Join(outputDir, page))
+	}
+	return renderTemplate("overview", OverviewHTML, map[string]interface{}{
+		"Package":   name,
+		"Overview":  string(g.markdownOffset([]byte(text), g.Markdown.HeadingOffset)),
+		"Readme":    readme,
+		"Files":     links,
+		"API":       api,
+		"Path":      filepath.ToSlash(source),
+		"SiteTitle": siteTitle,
+		"Index":     indexPage(),
+		"Root":      rootOf(dest),
+		"Analytics": analytics,
+	})
+}
+
+// With `-by-package` every package directory gets an `index.html` of the
+// same kind, listing all of its files under the package comment, if any,
+// and the directory's README, written for GitHub
+func writePackageIndexes() {
+	for i, source := range sources {
+		dir := command.packageStarting(i)
+		if dir == "" || pageDirOf(dir) == "" {
+			continue
+		}
+		var files []string
+		name, text := filepath.Base(dir), ""
+		for _, s := range sources[i:] {
+			if filepath.ToSlash(filepath.Dir(s)) != dir {
+				break
+			}
+			if !drafts[s] {
+				files = append(files, s)
+			}
+			if filepath.Ext(s) != ".go" || strings.HasSuffix(s, "_test.go") {
+				continue
+			}
+			code, err := readSource(s)
+			if err != nil {
+				continue
+			}
+			file, err := parser.ParseFile(token.NewFileSet(), s, code, parser.ParseComments|parser.PackageClauseOnly)
+			if err != nil {
+				continue
+			}
+			name = file.Name.Name
+			if text == "" {
+				text = file.Doc.Text()
+			}
+		}
+		dest := filepath.Join(outputDir, pageDirOf(dir), "index.html")
+		readme := command.renderReadme(filepath.FromSlash(dir))
+		html, err := command.renderOverview(dest, source, name, text, readme, files)
+		if err == nil {
+			err = writeFile(dest, html)
+		}
+		if err != nil {
+			renderFailure(err)
+			continue
+		}
+		log.Println("gocco: package ", dir, " -> ", dest)
+	}
+}
+
+// the names a README goes by, in the order they're looked for
+var readmeNames = []string{"README.md", "README.markdown", "readme.md", "README"}
+
+// `renderReadme` renders the README of a directory for the top of its
+// index page, empty if it has none. Its links and images are relative
+// to the directory, they're made to work from `docs/` like those in
+// comments
+func (g *Generator) renderReadme(dir string) string {
+	for _, name := range readmeNames {
+		file := filepath.Join(dir, name)
+		text, err := readSource(file)
+		if err != nil {
+			continue
+		}
+		sections := new(list.List)
+		sections.PushBack(&Section{text, nil, g.markdownOffset(text, g.Markdown.HeadingOffset), nil, file, 1, 1, contentHash(text, nil)})
+		g.rewriteSourceLinks(file, sections)
+		embedImages(file, sections)
+		return string(sections.Front().Value.(*Section).DocsHTML)
+	}
+	return ""
+}
@@ -0,0 +1,125 @@
+package gocco
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ## PDF output
+
+// Docs handed to someone offline, or kept with a release, are easier to
+// pass around as a file than as a directory of pages. With `-format pdf`
+// gocco builds the pages as usual and then prints each one to
+// `docs/<name>.pdf` next to it, with a headless Chrome or Chromium. The
+// print rules in `gocco.css` leave out the header, menus and controls
+// and unfold collapsed code, so the PDF holds the docs and code alone.
+//
+// The browser is looked for on the `PATH` under its usual names, or
+// given with `-chrome`
+var chromePath string
+
+// the names Chrome and Chromium go by
+var chromeNames = []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "chrome"}
+
+// the pages written for `-format pdf`, by where they go, with their
+// sources
+var (
+	pdfPages     = make(map[string]string)
+	pdfPagesLock sync.Mutex
+)
+
+// the browser printing the pages, found by `findChrome`
+var chrome string
+
+// `findChrome` settles on the browser printing the pages, before any
+// page is built
+func findChrome() error {
+	if chromePath != "" {
+		path, err := exec.LookPath(chromePath)
+		chrome = path
+		return err
+	}
+	for _, name := range chromeNames {
+		if path, err := exec.LookPath(name); err == nil {
+			chrome = path
+			return nil
+		}
+	}
+	return fmt.Errorf("no Chrome or Chromium found, install one or give it with -chrome")
+}
+
+// where the PDF of a page goes
+func pdfDestination(page string) string {
+	return strings.TrimSuffix(page, ".html") + ".pdf"
+}
+
+// remember the page of `source` for `writePDFs`
+func addPDFPage(page, source string) {
+	pdfPagesLock.Lock()
+	pdfPages[page] = source
+	pdfPagesLock.Unlock()
+}
+
+// `writePDFs` prints every page written since it last ran
+func writePDFs() {
+	pdfPagesLock.Lock()
+	pages := pdfPages
+	pdfPages = make(map[string]string)
+	pdfPagesLock.Unlock()
+
+	var names []string
+	for page := range pages {
+		names = append(names, page)
+	}
+	sort.Strings(names)
+	for _, page := range names {
+		// a page held back for a broken link was never written
+		if _, err := os.Stat(page); err != nil {
+			continue
+		}
+		dest := pdfDestination(page)
+		if err := printPDF(page, dest, pages[page]); err != nil {
+			renderFailure(pages[page], ": ", err)
+			continue
+		}
+		log.Println("gocco: ", page, " -> ", dest)
+	}
+}
+
+// print the page at `page` to `dest`
+func printPDF(page, dest, source string) error {
+	from, err := filepath.Abs(page)
+	if err != nil {
+		return err
+	}
+	to, err := filepath.Abs(dest)
+	if err != nil {
+		return err
+	}
+	// drive letters make a path of their own on Windows
+	path := filepath.ToSlash(from)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	// the scripts get a moment to highlight and lay out the page before
+	// it's printed
+	printing := exec.Command(chrome, "--headless", "--disable-gpu", "--no-pdf-header-footer",
+		"--virtual-time-budget=2000", "--print-to-pdf="+to, (&url.URL{Scheme: "file", Path: path}).String())
+	if output, err := printing.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %v\n%s", filepath.Base(chrome), err, output)
+	}
+	data, err := ioutil.ReadFile(to)
+	if err != nil {
+		return err
+	}
+	addToManifest(dest, source, data)
+	return os.Chmod(dest, fileMode)
+}
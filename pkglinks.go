@@ -0,0 +1,126 @@
+package gocco
+
+import (
+	"container/list"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"html"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ## Links to pkg.go.dev
+
+// In the code of a Go source, a use of an imported package such as
+// `http.ListenAndServe` links to the documentation of the symbol on
+// pkg.go.dev. The package is looked up in the imports of the file, so
+// renamed imports work too, and a local name shadowing the package, like
+// `url` in `url := u.String()`, is left alone. A source that doesn't
+// parse only has its imports read, and links every selector on a name
+// it imports. Turn it off with `-pkg-links=false`, or `PkgLinks` of a
+// `Generator`
+var pkgLinks = true
+
+// a selector as Pygments highlights it: a name, a dot and an exported
+// name
+var selectorPattern = regexp.MustCompile(`<span class="n[a-z]?">(\w+)</span><span class="p">\.</span><span class="n[a-z]?">([A-Z]\w*)</span>`)
+
+// the import paths of a Go source by the name they're used under
+func importedPackages(source string, code []byte) map[string]string {
+	file, err := parser.ParseFile(token.NewFileSet(), source, code, parser.ImportsOnly)
+	if err != nil {
+		return nil
+	}
+	imports := make(map[string]string)
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := packageName(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if name != "_" && name != "." {
+			imports[name] = importPath
+		}
+	}
+	return imports
+}
+
+// whether each selector on a name of `source`, in order, is one on an
+// imported package. It's nil when the source doesn't parse
+func packageSelectors(source string, code []byte, imports map[string]string) []bool {
+	file, err := parser.ParseFile(token.NewFileSet(), source, code, 0)
+	if err != nil {
+		return nil
+	}
+	onPackage := []bool{}
+	ast.Inspect(file, func(node ast.Node) bool {
+		if selector, ok := node.(*ast.SelectorExpr); ok && selector.Sel.IsExported() {
+			if name, ok := selector.X.(*ast.Ident); ok {
+				// names declared in the file resolve to their object,
+				// packages don't
+				_, imported := imports[name.Name]
+				onPackage = append(onPackage, imported && name.Obj == nil)
+			}
+		}
+		return true
+	})
+	return onPackage
+}
+
+// the name a package is used under by default, the last element of its
+// path leaving out a major version like `/v2` or `.v2`
+func packageName(importPath string) string {
+	name := path.Base(importPath)
+	if len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = path.Base(path.Dir(importPath))
+	}
+	if i := strings.Index(name, ".v"); i > 0 {
+		name = name[:i]
+	}
+	return strings.TrimPrefix(strings.Replace(name, "-", "_", -1), "go_")
+}
+
+// `linkPackages` links the selectors on imported packages in the code
+// of every section
+func (g *Generator) linkPackages(source string, code []byte, sections *list.List) {
+	if !g.PkgLinks || getLanguage(source).name != "go" {
+		return
+	}
+	imports := importedPackages(source, code)
+	if len(imports) == 0 {
+		return
+	}
+	// the highlighted selectors are matched up with the parsed ones by
+	// their order, which only works when there are as many of each
+	onPackage := packageSelectors(source, code, imports)
+	count := 0
+	for e := sections.Front(); e != nil; e = e.Next() {
+		count += len(selectorPattern.FindAllIndex(e.Value.(*Section).CodeHTML, -1))
+	}
+	if count != len(onPackage) {
+		onPackage = nil
+	}
+	i := 0
+	for e := sections.Front(); e != nil; e = e.Next() {
+		section := e.Value.(*Section)
+		section.CodeHTML = selectorPattern.ReplaceAllFunc(section.CodeHTML, func(match []byte) []byte {
+			parts := selectorPattern.FindSubmatch(match)
+			importPath, ok := imports[string(parts[1])]
+			if onPackage != nil {
+				ok = ok && onPackage[i]
+				i++
+			}
+			if !ok {
+				return match
+			}
+			href := "https://pkg.go.dev/" + importPath + "#" + string(parts[2])
+			return []byte(`<a class="pkg" href="` + html.EscapeString(href) + `">` + string(match) + `</a>`)
+		})
+	}
+}
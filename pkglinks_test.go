@@ -0,0 +1,62 @@
+package gocco
+
+import (
+	"container/list"
+	"strings"
+	"testing"
+)
+
+func TestLinkPackages(t *testing.T) {
+	setupOnce.Do(setup)
+	// a selector as Pygments highlights it
+	sel := func(name, exported string) string {
+		return `<span class="n">` + name + `</span><span class="p">.</span><span class="n">` + exported + `</span>`
+	}
+	link := func(href, name, exported string) string {
+		return `<a class="pkg" href="` + href + `">` + sel(name, exported) + `</a>`
+	}
+	tests := []struct {
+		name string
+		code string
+		html []string
+		want []string
+	}{
+		{"imported", "package a\nimport \"net/http\"\nfunc f() { http.Get(\"\") }\n",
+			[]string{sel("http", "Get")},
+			[]string{link("https://pkg.go.dev/net/http#Get", "http", "Get")}},
+		{"renamed", "package a\nimport h \"net/http\"\nfunc f() { h.Get(\"\") }\n",
+			[]string{sel("h", "Get")},
+			[]string{link("https://pkg.go.dev/net/http#Get", "h", "Get")}},
+		{"major version", "package a\nimport \"example.com/yaml/v2\"\nvar _ = yaml.Marshal\n",
+			[]string{sel("yaml", "Marshal")},
+			[]string{link("https://pkg.go.dev/example.com/yaml/v2#Marshal", "yaml", "Marshal")}},
+		{"not imported", "package a\nimport \"os\"\nfunc f(t T) { t.Run(); os.Exit(1) }\n",
+			[]string{sel("t", "Run"), sel("os", "Exit")},
+			[]string{sel("t", "Run"), link("https://pkg.go.dev/os#Exit", "os", "Exit")}},
+		{"shadowed", "package a\nimport \"os\"\nfunc f() { os.Exit(1) }\nfunc g() { os := x(); os.Exit(1) }\n",
+			[]string{sel("os", "Exit"), sel("os", "Exit")},
+			[]string{link("https://pkg.go.dev/os#Exit", "os", "Exit"), sel("os", "Exit")}},
+		{"unparsed", "package a\nimport \"os\"\nfunc f() { os.Exit(1) \n",
+			[]string{sel("os", "Exit")},
+			[]string{link("https://pkg.go.dev/os#Exit", "os", "Exit")}},
+		{"escaped", "package a\nimport b \"example.com/a&b\"\nvar _ = b.C\n",
+			[]string{sel("b", "C")},
+			[]string{link("https://pkg.go.dev/example.com/a&amp;b#C", "b", "C")}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sections := list.New()
+			for _, html := range test.html {
+				sections.PushBack(&Section{CodeHTML: []byte(html)})
+			}
+			NewGenerator().linkPackages("a.go", []byte(test.code), sections)
+			var got []string
+			for e := sections.Front(); e != nil; e = e.Next() {
+				got = append(got, string(e.Value.(*Section).CodeHTML))
+			}
+			if strings.Join(got, "\n") != strings.Join(test.want, "\n") {
+				t.Errorf("linked\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(test.want, "\n"))
+			}
+		})
+	}
+}
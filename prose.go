@@ -0,0 +1,164 @@
+package gocco
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ## Prose rules
+
+// Besides spelling, the docs can be held to a house style in the manner
+// of Vale: sentences that run on, the passive voice and words a team
+// would rather not see. The rules come from a JSON file:
+//
+//     {
+//       "max_sentence_words": 30,
+//       "passive_voice": true,
+//       "banned": {"simply": "", "utilize": "use"}
+//     }
+//
+// A banned word maps to the word to use instead, if there is one
+type ProseRules struct {
+	MaxSentenceWords int               `json:"max_sentence_words"`
+	PassiveVoice     bool              `json:"passive_voice"`
+	Banned           map[string]string `json:"banned"`
+}
+
+// the rules used without a rules file
+var proseRules = &ProseRules{
+	MaxSentenceWords: 40,
+	Banned: map[string]string{
+		"basically": "",
+		"obviously": "",
+		"simply":    "",
+		"utilize":   "use",
+	},
+}
+
+// compiled from the banned words
+var bannedPattern *regexp.Regexp
+
+// whether generating the docs also checks the prose rules, reporting
+// problems as it goes, set with `-prose`
+var checkProse bool
+
+// read the prose rules, keeping the defaults when the file is missing
+// and not `required`
+func loadProseRules(file string, required bool) error {
+	data, err := ioutil.ReadFile(file)
+	if err == nil {
+		rules := new(ProseRules)
+		if err := json.Unmarshal(data, rules); err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+		proseRules = rules
+	} else if required || !os.IsNotExist(err) {
+		return err
+	}
+	if len(proseRules.Banned) > 0 {
+		var words []string
+		for word := range proseRules.Banned {
+			words = append(words, regexp.QuoteMeta(word))
+		}
+		bannedPattern = regexp.MustCompile(`(?i)\b(` + strings.Join(words, "|") + `)\b`)
+	}
+	return nil
+}
+
+// a form of "to be" followed by a past participle, regular or one of the
+// common irregular ones
+var passivePattern = regexp.MustCompile(`(?i)\b(am|is|are|was|were|be|been|being)\s+` +
+	`(\w+ed|born|brought|built|chosen|done|drawn|driven|found|given|gone|held|hidden|` +
+	`kept|known|left|made|meant|put|read|run|said|seen|sent|set|shown|taken|thrown|told|written)\b`)
+
+var sentenceEnd = regexp.MustCompile(`[.!?](\s|$)`)
+
+// `lintProse` checks the doc lines of a source against the prose rules.
+// Sentences can span lines, so the lines of each paragraph are joined
+// and a problem is reported at the line its sentence starts on
+func lintProse(source string, lines []*DocLine) []*Problem {
+	var problems []*Problem
+	var paragraph []*DocLine
+	flush := func() {
+		problems = append(problems, lintParagraph(source, paragraph)...)
+		paragraph = nil
+	}
+	for i, line := range lines {
+		// a blank line, a gap in the comment or a new section all end
+		// the paragraph
+		if i > 0 && (line.Number != lines[i-1].Number+1 || line.Section != lines[i-1].Section) {
+			flush()
+		}
+		if strings.TrimSpace(line.Text) == "" {
+			flush()
+			continue
+		}
+		paragraph = append(paragraph, line)
+	}
+	flush()
+	return problems
+}
+
+func lintParagraph(source string, lines []*DocLine) []*Problem {
+	if len(lines) == 0 {
+		return nil
+	}
+	// join the lines, remembering where each one starts
+	var text strings.Builder
+	starts := make([]int, len(lines))
+	for i, line := range lines {
+		starts[i] = text.Len()
+		text.WriteString(notProse.ReplaceAllString(line.Text, "x"))
+		text.WriteString(" ")
+	}
+	lineAt := func(offset int) *DocLine {
+		at := lines[0]
+		for i, start := range starts {
+			if start <= offset {
+				at = lines[i]
+			}
+		}
+		return at
+	}
+	var problems []*Problem
+	report := func(offset int, message string) {
+		line := lineAt(offset)
+		problems = append(problems, &Problem{source, line.Number, line.Section, message})
+	}
+
+	prose := text.String()
+	start := 0
+	for start < len(prose) {
+		end := len(prose)
+		if loc := sentenceEnd.FindStringIndex(prose[start:]); loc != nil {
+			end = start + loc[1]
+		}
+		sentence := prose[start:end]
+		if max := proseRules.MaxSentenceWords; max > 0 {
+			if n := len(strings.Fields(sentence)); n > max {
+				report(start, fmt.Sprintf("sentence has %d words, more than %d", n, max))
+			}
+		}
+		if proseRules.PassiveVoice {
+			for _, loc := range passivePattern.FindAllStringIndex(sentence, -1) {
+				report(start+loc[0], fmt.Sprintf("passive voice %q", sentence[loc[0]:loc[1]]))
+			}
+		}
+		if bannedPattern != nil {
+			for _, loc := range bannedPattern.FindAllStringIndex(sentence, -1) {
+				word := sentence[loc[0]:loc[1]]
+				message := fmt.Sprintf("avoid %q", word)
+				if instead := proseRules.Banned[strings.ToLower(word)]; instead != "" {
+					message += fmt.Sprintf(", use %q", instead)
+				}
+				report(start+loc[0], message)
+			}
+		}
+		start = end
+	}
+	return problems
+}
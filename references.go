@@ -0,0 +1,106 @@
+package gocco
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"html"
+	"path/filepath"
+	"regexp"
+	"sync/atomic"
+)
+
+// ## References between chapters
+
+// A comment can point at a heading of another chapter with
+// `[[parse.go#splitting-sections]]`, the file relative to the commenting
+// one and the slug the heading's anchor. The reference becomes a link
+// titled after the heading; one to a file or heading that isn't
+// documented is an error, and makes gocco exit with a failure status
+
+var referencePattern = regexp.MustCompile(`\[\[([^\]#\s]+)#([^\]\s]+)\]\]`)
+
+// references quoted as code are left as they are
+var codeOrReferencePattern = regexp.MustCompile(`(?s)<code>.*?</code>|` + referencePattern.String())
+
+// the number of references that could not be resolved
+var danglingReferences int32
+
+// `headingsOf` renders the docs of `source` to find its heading anchors,
+// the same way its own page will
+func (g *Generator) headingsOf(source string) map[string]string {
+	g.headingsLock.Lock()
+	texts, ok := g.headings[source]
+	g.headingsLock.Unlock()
+	if ok {
+		return texts
+	}
+	// pages referring to each other are rendered in parallel, so the
+	// lock is only held for the cache. Two of them may render the same
+	// source at once, the first to finish is kept
+	texts = make(map[string]string)
+	code, err := readSource(source)
+	if err == nil {
+		sections := parse(source, code)
+		extractFrontMatter(source, sections)
+		// only the ids and texts of the headings are wanted, not their level
+		g.renderMarkdown(sections, 0)
+		for _, heading := range anchorHeadings(sections) {
+			texts[heading.ID] = heading.Text
+		}
+	}
+	g.headingsLock.Lock()
+	defer g.headingsLock.Unlock()
+	if cached, ok := g.headings[source]; ok {
+		return cached
+	}
+	if g.headings == nil {
+		g.headings = make(map[string]map[string]string)
+	}
+	g.headings[source] = texts
+	return texts
+}
+
+// `resolveReferences` replaces every reference in the docs with a link
+// to the page and heading it names. On a joined page the `headings` of
+// the page give the ids the headings ended up with there
+func (g *Generator) resolveReferences(source string, sections *list.List, headings []*Heading) {
+	documented := make(map[string]string)
+	for _, s := range g.Sources {
+		documented[filepath.Clean(s)] = s
+	}
+	joined := make(map[string]string)
+	for _, heading := range headings {
+		joined[filepath.Clean(heading.source)+"#"+heading.local] = heading.ID
+	}
+	for e := sections.Front(); e != nil; e = e.Next() {
+		section := e.Value.(*Section)
+		section.DocsHTML = codeOrReferencePattern.ReplaceAllFunc(section.DocsHTML, func(match []byte) []byte {
+			parts := referencePattern.FindSubmatch(match)
+			if parts == nil || !bytes.HasPrefix(match, []byte("[[")) {
+				return match
+			}
+			file, id := html.UnescapeString(string(parts[1])), string(parts[2])
+			target, ok := documented[filepath.Join(filepath.Dir(section.source), filepath.FromSlash(file))]
+			if !ok {
+				annotate("error", section.source, 0, "reference to undocumented file "+string(match))
+				atomic.AddInt32(&danglingReferences, 1)
+				return match
+			}
+			text, ok := g.headingsOf(target)[id]
+			if !ok {
+				annotate("error", section.source, 0, "reference to missing heading "+string(match))
+				atomic.AddInt32(&danglingReferences, 1)
+				return match
+			}
+			href := pageLink(source, target) + "#" + id
+			if joinName != "" {
+				href = "#" + id
+				if joinedID, ok := joined[filepath.Clean(target)+"#"+id]; ok {
+					href = "#" + joinedID
+				}
+			}
+			return []byte(fmt.Sprintf(`<a class="reference" href="%s">%s</a>`, href, html.EscapeString(text)))
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package gocco
+
+import (
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestHeadingsOf(t *testing.T) {
+	setupOnce.Do(setup)
+	dir := makeTree(t, map[string]string{
+		"a.go": "// # Reading\n//\n// ## Closing up\npackage a\n",
+	})
+	source := filepath.Join(dir, "a.go")
+	want := map[string]string{"reading": "Reading", "closing-up": "Closing up"}
+	g := NewGenerator(source)
+	results := make([]map[string]string, 8)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = g.headingsOf(source)
+		}(i)
+	}
+	wg.Wait()
+	for _, got := range results {
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("headings %v, want %v", got, want)
+		}
+	}
+	if got := g.headingsOf(filepath.Join(dir, "missing.go")); len(got) != 0 {
+		t.Errorf("headings of a missing source %v", got)
+	}
+}
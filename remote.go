@@ -0,0 +1,114 @@
+package gocco
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ## Remote sources
+
+// An `http://` or `https://` address among the inputs is downloaded and
+// documented like a local file, named after its host and path, so
+// `gocco https://raw.githubusercontent.com/nikhilm/gocco/master/gocco.go`
+// gives `docs/raw.githubusercontent.com/nikhilm/gocco/master/gocco.html`.
+// Downloads larger than `-max-download` bytes are refused
+var maxDownload int64 = 5 << 20
+
+// Downloads are kept in the user's cache directory. The next run only
+// asks the server whether the file changed, and falls back on the cached
+// copy when it can't be reached
+var downloadClient = &http.Client{Timeout: 30 * time.Second}
+
+// whether an input is an address rather than a file
+func isURL(arg string) bool {
+	return strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://")
+}
+
+// the name a downloaded source goes by
+func remoteName(u *url.URL) string {
+	return path.Clean(u.Host + "/" + strings.TrimPrefix(u.Path, "/"))
+}
+
+// where the download of `address` and its ETag are cached, or an empty
+// string when there is no cache directory
+func cacheFile(address string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "gocco", fmt.Sprintf("%x", sha256.Sum256([]byte(address))))
+}
+
+// `download` fetches a source and returns the name it's documented
+// under
+func download(address string) (string, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", err
+	}
+	name := remoteName(u)
+	cached := cacheFile(address)
+	var old []byte
+	if cached != "" {
+		old, _ = ioutil.ReadFile(cached)
+	}
+
+	request, err := http.NewRequest("GET", address, nil)
+	if err != nil {
+		return "", err
+	}
+	if etag, err := ioutil.ReadFile(cached + ".etag"); err == nil && old != nil {
+		request.Header.Set("If-None-Match", string(etag))
+	}
+	response, err := downloadClient.Do(request)
+	if err != nil {
+		if old == nil {
+			return "", err
+		}
+		warn(address, 0, "using the cached copy, "+err.Error())
+		keepSource(name, old)
+		return name, nil
+	}
+	defer response.Body.Close()
+
+	switch {
+	case response.StatusCode == http.StatusNotModified && old != nil:
+		keepSource(name, old)
+		return name, nil
+	case response.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("%s: %s", address, response.Status)
+	case response.ContentLength > maxDownload:
+		return "", fmt.Errorf("%s: %d bytes, larger than -max-download", address, response.ContentLength)
+	}
+	// the length isn't always announced, so reading stops one byte past
+	// the limit to notice going over it
+	data, err := ioutil.ReadAll(io.LimitReader(response.Body, maxDownload+1))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(data)) > maxDownload {
+		return "", fmt.Errorf("%s: larger than -max-download", address)
+	}
+	keepSource(name, data)
+
+	if cached != "" {
+		os.MkdirAll(filepath.Dir(cached), 0755)
+		if err := ioutil.WriteFile(cached, data, 0644); err == nil {
+			if etag := response.Header.Get("ETag"); etag != "" {
+				ioutil.WriteFile(cached+".etag", []byte(etag), 0644)
+			} else {
+				os.Remove(cached + ".etag")
+			}
+		}
+	}
+	return name, nil
+}
@@ -0,0 +1,110 @@
+package gocco
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRemoteName(t *testing.T) {
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{"https://raw.githubusercontent.com/nikhilm/gocco/master/gocco.go", "raw.githubusercontent.com/nikhilm/gocco/master/gocco.go"},
+		{"http://example.com:8080/a/../b.go", "example.com:8080/b.go"},
+		{"https://example.com/a.go?raw=1", "example.com/a.go"},
+	}
+	for _, test := range tests {
+		u, err := url.Parse(test.address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := remoteName(u); got != test.want {
+			t.Errorf("remoteName(%q) = %q, want %q", test.address, got, test.want)
+		}
+	}
+}
+
+func TestRemoteDestination(t *testing.T) {
+	defer func(dir string) { outputDir = dir }(outputDir)
+	outputDir = "docs"
+	want := filepath.FromSlash("docs/raw.githubusercontent.com/nikhilm/gocco/master/gocco.html")
+	if got := destination("raw.githubusercontent.com/nikhilm/gocco/master/gocco.go"); got != want {
+		t.Errorf("remote page goes to %q, want %q", got, want)
+	}
+}
+
+func TestDownload(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("LocalAppData", t.TempDir())
+	defer func(max int64) { maxDownload = max }(maxDownload)
+	maxDownload = 16
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		switch r.URL.Path {
+		case "/a.go":
+			if r.Header.Get("If-None-Match") == `"1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"1"`)
+			w.Write([]byte("package a\n"))
+		case "/large.go":
+			w.Write([]byte(strings.Repeat("/", 17)))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	tests := []struct {
+		name string
+		path string
+		want string
+		err  string
+	}{
+		{"download", "/a.go", "package a\n", ""},
+		{"cached", "/a.go", "package a\n", ""},
+		{"missing", "/b.go", "", "404 Not Found"},
+		{"too large", "/large.go", "", "larger than -max-download"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			name, err := download(server.URL + test.path)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error %v, want one about %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if name != host+test.path {
+				t.Errorf("named %q, want %q", name, host+test.path)
+			}
+			if code, _ := readSource(name); string(code) != test.want {
+				t.Errorf("read %q, want %q", code, test.want)
+			}
+		})
+	}
+	if requests["/a.go"] != 2 {
+		t.Errorf("%d requests for /a.go, want 2", requests["/a.go"])
+	}
+
+	server.Close()
+	name, err := download(server.URL + "/a.go")
+	if err != nil {
+		t.Fatalf("the cached copy isn't used offline: %v", err)
+	}
+	if code, _ := readSource(name); string(code) != "package a\n" {
+		t.Errorf("read %q offline", code)
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package gocco
 
 var Css = `
 /*--------------------- Layout and Typography ----------------------------*/
@@ -24,12 +24,86 @@ h1, h2, h3, h4, h5, h6 {
   h1 {
     margin-top: 40px;
   }
+:root {
+  --docs-width: 450px;
+}
 #container {
   position: relative;
+  padding-top: 25px;
+}
+#header {
+  position: fixed;
+  top: 0; left: 0; right: 0;
+  height: 24px;
+  padding: 0 10px;
+  background: white;
+  border-bottom: 1px solid #e5e5ee;
+  font: 11px/24px Arial;
+  z-index: 2;
+}
+  #header .site {
+    font-weight: bold;
+    margin-right: 15px;
+  }
+  #header .path {
+    font-family: Menlo, Monaco, Consolas, "Lucida Console", monospace;
+  }
+  #header .index, #header .raw {
+    margin-left: 15px;
+    text-transform: uppercase;
+    font-size: 10px;
+  }
+  #header .search {
+    display: inline;
+    margin-left: 15px;
+  }
+    #header .search input, #header .find input {
+      font: 11px Arial;
+      padding: 1px 4px;
+      border: 1px solid #e5e5ee;
+    }
+  #header .find {
+    display: inline;
+    margin-left: 15px;
+    font: 11px Arial;
+    color: #777;
+  }
+  td.code span.found {
+    background: #fff3a0;
+  }
+    td.code span.found.current {
+      background: #ffd54a;
+      outline: 1px solid #e0a800;
+    }
+#footer {
+  padding: 10px 25px 20px 50px;
+  font: 11px Arial;
+  color: #777;
+}
+#results {
+  list-style: none;
+  padding: 0;
+}
+  #results li {
+    margin: 0 0 15px 0;
+  }
+tr[id^=section-], tr.file, .docs [id] {
+  scroll-margin-top: 25px;
+}
+  tr.targeted td {
+    -webkit-animation: targeted 2s ease-out;
+    animation: targeted 2s ease-out;
+  }
+@-webkit-keyframes targeted {
+  from { background-color: #ffffcc; }
+}
+@keyframes targeted {
+  from { background-color: #ffffcc; }
 }
 #background {
   position: fixed;
   top: 0; left: 525px; right: 0; bottom: 0;
+  left: calc(var(--docs-width) + 75px);
   background: #f5f5ff;
   border-left: 1px solid #e5e5ee;
   z-index: -1;
@@ -70,6 +144,22 @@ h1, h2, h3, h4, h5, h6 {
         }
         #jump_page .source:first-child {
         }
+      #jump_page .part {
+        padding: 8px 10px 3px;
+        font-weight: bold;
+        cursor: default;
+      }
+#splitter {
+  position: fixed;
+  top: 0; bottom: 0;
+  left: calc(var(--docs-width) + 72px);
+  width: 6px;
+  cursor: col-resize;
+  z-index: 1;
+}
+  #splitter:hover, #splitter.dragging {
+    background: #e5e5ee;
+  }
 table td {
   border: 0;
   outline: 0;
@@ -77,12 +167,75 @@ table td {
   td.docs, th.docs {
     max-width: 450px;
     min-width: 450px;
+    max-width: var(--docs-width);
+    min-width: var(--docs-width);
     min-height: 5px;
     padding: 10px 25px 1px 50px;
     overflow-x: hidden;
     vertical-align: top;
     text-align: left;
   }
+    td.overview, th.overview {
+      max-width: none;
+      min-width: 0;
+      width: 100%;
+    }
+    .readme {
+      border-bottom: 1px solid #e5e5ee;
+      margin-bottom: 15px;
+    }
+    a.pkg {
+      color: inherit;
+      text-decoration: none;
+    }
+      a.pkg:hover {
+        text-decoration: underline;
+      }
+    .media video, .media iframe {
+      max-width: 100%;
+      border: 0;
+    }
+      .media iframe {
+        width: 100%;
+        aspect-ratio: 16 / 9;
+      }
+    .godoc {
+      border-left: 3px solid #e5e5ee;
+      padding-left: 12px;
+      margin: 15px 0;
+    }
+    .api .funcs {
+      margin-left: 2em;
+    }
+    .docs h1, .docs h2, .docs h3, .docs h4, .docs h5, .docs h6 {
+      position: relative;
+    }
+      .docs .anchor {
+        position: absolute;
+        left: -18px;
+        font: 12px Arial;
+        line-height: inherit;
+        text-decoration: none;
+        color: #454545;
+        opacity: 0;
+        -webkit-transition: opacity 0.2s linear;
+      }
+        .docs h1:hover .anchor, .docs h2:hover .anchor, .docs h3:hover .anchor,
+        .docs h4:hover .anchor, .docs h5:hover .anchor, .docs h6:hover .anchor {
+          opacity: 1;
+        }
+    .docs .toc {
+      margin: 0 0 15px 0;
+    }
+      .docs .toc ul {
+        margin: 0;
+        padding-left: 20px;
+      }
+    .docs h2.file {
+      margin-top: 40px;
+      font-family: Menlo, Monaco, Consolas, "Lucida Console", monospace;
+      font-size: 16px;
+    }
     .docs pre {
       margin: 15px 0 15px;
       padding-left: 15px;
@@ -109,6 +262,28 @@ table td {
         td.docs:hover .pilcrow {
           opacity: 1;
         }
+    .sticky-docs td.docs {
+      overflow: visible;
+    }
+    td.docs.balanced {
+      position: relative;
+    }
+      td.docs .recap {
+        position: absolute;
+        left: 50px; right: 25px;
+        color: #aaa;
+        font-style: italic;
+        font-size: 13px;
+        line-height: 18px;
+        white-space: nowrap;
+        overflow: hidden;
+        text-overflow: ellipsis;
+      }
+      .sticky-docs .section-docs {
+        position: -webkit-sticky;
+        position: sticky;
+        top: 35px;
+      }
   td.code, th.code {
     padding: 14px 15px 16px 25px;
     width: 100%;
@@ -121,7 +296,167 @@ table td {
       font-family: Menlo, Monaco, Consolas, "Lucida Console", monospace;
       margin: 0; padding: 0;
     }
+  td.code {
+    position: relative;
+  }
+    td.code .copy, td.code .fold {
+      font: 10px Arial;
+      text-transform: uppercase;
+      position: absolute;
+      top: 5px; right: 5px;
+      padding: 2px 6px;
+      background: white;
+      border: 1px solid #e5e5ee;
+      cursor: pointer;
+      opacity: 0;
+      -webkit-transition: opacity 0.2s linear;
+    }
+      td.code:hover .copy, td.code:hover .fold {
+        opacity: 1;
+      }
+    td.code .fold {
+      right: 50px;
+    }
+    td.code .folded {
+      display: none;
+      font: 12px Arial;
+      color: #454545;
+      background: none;
+      border: 0;
+      padding: 0;
+      cursor: pointer;
+    }
+      td.code.collapsed .folded {
+        display: block;
+      }
+      td.code.collapsed pre, td.code.collapsed .highlighttable {
+        display: none;
+      }
+    td.code .highlighttable {
+      border-collapse: collapse;
+    }
+      td.code .highlighttable td {
+        padding: 0;
+        vertical-align: top;
+      }
+      td.code .highlighttable td.linenos {
+        padding-right: 10px;
+        color: #aaa;
+        text-align: right;
+        user-select: none;
+      }
+    td.code .wrap-lines pre {
+      white-space: pre-wrap;
+      overflow-wrap: anywhere;
+    }
+
+
+/*---------------------- Linear layout -----------------------------------*/
+.linear #background, .linear #splitter, .linear th.code, .linear td.docs .recap {
+  display: none;
+}
+.linear table, .linear thead, .linear tbody, .linear tr, .linear td, .linear th {
+  display: block;
+}
+.linear td.docs, .linear th.docs, .linear td.code {
+  max-width: 760px;
+  min-width: 0;
+  margin: 0 auto;
+  box-sizing: border-box;
+}
+.linear td.docs, .linear th.docs {
+  padding: 10px 20px 1px;
+}
+.linear td.code {
+  width: auto;
+  margin-bottom: 15px;
+  padding: 10px 15px;
+  border: 1px solid #e5e5ee;
+  border-radius: 3px;
+  overflow-x: auto;
+}
+.linear.sticky-docs .section-docs {
+  position: static;
+}
 
+/*---------------------- Right-to-left -----------------------------------*/
+[dir=rtl] #background {
+  left: 0; right: 525px;
+  right: calc(var(--docs-width) + 75px);
+  border-left: 0;
+  border-right: 1px solid #e5e5ee;
+}
+[dir=rtl] #splitter {
+  left: auto;
+  right: calc(var(--docs-width) + 72px);
+}
+[dir=rtl] #jump_to, [dir=rtl] #jump_wrapper {
+  right: auto; left: 0;
+}
+[dir=rtl] #jump_to, [dir=rtl] #jump_page {
+  text-align: left;
+}
+  [dir=rtl] #jump_page {
+    margin: 0 25px 25px 0;
+  }
+[dir=rtl] td.docs, [dir=rtl] th.docs {
+  padding: 10px 50px 1px 25px;
+  text-align: right;
+}
+  [dir=rtl] .pilcrow {
+    left: auto; right: -20px;
+  }
+  [dir=rtl] .docs .anchor {
+    left: auto; right: -18px;
+  }
+  [dir=rtl] .docs pre {
+    direction: ltr;
+    text-align: left;
+    padding-left: 0;
+    padding-right: 15px;
+  }
+[dir=rtl] td.code, [dir=rtl] th.code {
+  direction: ltr;
+  text-align: left;
+  border-left: 0;
+  border-right: 1px solid #e5e5ee;
+}
+
+/*---------------------- Print -------------------------------------------*/
+@media print {
+  #header, #background, #splitter, #jump_to, #results, .pilcrow, .docs .anchor,
+  td.code .fold, td.code .folded {
+    display: none !important;
+  }
+  #container {
+    padding-top: 0;
+  }
+  td.code.collapsed pre {
+    display: block;
+  }
+  td.code.collapsed .highlighttable {
+    display: table;
+  }
+  td.docs, th.docs {
+    min-width: 0;
+    max-width: none;
+    width: 40%;
+    padding-left: 0;
+  }
+  td.code pre {
+    white-space: pre-wrap;
+    overflow-wrap: anywhere;
+  }
+  h1, h2, h3, h4, h5, h6 {
+    break-after: avoid;
+  }
+  pre, img {
+    break-inside: avoid;
+  }
+  a {
+    color: inherit;
+  }
+}
 
 /*---------------------- Syntax Highlighting -----------------------------*/
 td.linenos { background-color: #f0f0f0; padding-right: 10px; }
@@ -189,38 +524,390 @@ body .vi { color: #19469D }                     /* Name.Variable.Instance */
 body .il { color: #666666 }                     /* Literal.Number.Integer.Long */
 `
 
+// Js holds the behaviour of the generated pages. It is inlined into every
+// page, or written to `gocco.js` when running with `-csp`, since a
+// Content-Security-Policy without 'unsafe-inline' would block it otherwise
+var Js = `
+// the words to use, in the language of the page
+var messages = JSON.parse(document.getElementById("gocco-messages").textContent);
+// with -highlighter client the code is coloured here, before anything
+// else looks at it
+if (window.hljs) {
+  hljs.highlightAll();
+}
+// add a copy button to every code cell that copies the plain code,
+// without the highlighting markup
+(function () {
+  var copy = function (text, button) {
+    var done = function () {
+      button.textContent = messages.copied;
+      setTimeout(function () { button.textContent = messages.copy; }, 1500);
+    };
+    if (navigator.clipboard) {
+      navigator.clipboard.writeText(text).then(done);
+      return;
+    }
+    var area = document.createElement("textarea");
+    area.value = text;
+    document.body.appendChild(area);
+    area.select();
+    document.execCommand("copy");
+    document.body.removeChild(area);
+    done();
+  };
+  var cells = document.querySelectorAll("td.code");
+  for (var i = 0; i < cells.length; i++) {
+    var pre = cells[i].querySelector(".highlight pre");
+    if (!pre || !pre.textContent.replace(/\s/g, "")) {
+      continue;
+    }
+    var button = document.createElement("button");
+    button.className = "copy";
+    button.textContent = messages.copy;
+    button.onclick = (function (pre, button) {
+      return function () { copy(pre.textContent, button); };
+    })(pre, button);
+    cells[i].insertBefore(button, cells[i].firstChild);
+  }
+})();
+
+// let long code cells be folded away. Cells marked data-collapsed by
+// -collapse start out folded
+(function () {
+  var cells = document.querySelectorAll("td.code");
+  for (var i = 0; i < cells.length; i++) {
+    var pre = cells[i].querySelector(".highlight pre");
+    if (!pre) {
+      continue;
+    }
+    var lines = pre.textContent.replace(/\n+$/, "").split("\n").length;
+    if (lines < 10 && !cells[i].hasAttribute("data-collapsed")) {
+      continue;
+    }
+    var toggle = document.createElement("button");
+    toggle.className = "fold";
+    var note = document.createElement("button");
+    note.className = "folded";
+    note.textContent = messages.show_lines.replace("{lines}", lines);
+    var fold = (function (cell, toggle) {
+      return function () {
+        var folded = cell.classList.toggle("collapsed");
+        toggle.textContent = folded ? messages.expand : messages.collapse;
+      };
+    })(cells[i], toggle);
+    toggle.onclick = fold;
+    note.onclick = fold;
+    cells[i].insertBefore(toggle, cells[i].firstChild);
+    cells[i].appendChild(note);
+    toggle.textContent = messages.collapse;
+    if (cells[i].hasAttribute("data-collapsed")) {
+      fold();
+    }
+  }
+})();
+
+// make the split between docs and code draggable, remembering the
+// chosen width across pages
+(function () {
+  var key = "gocco-docs-width";
+  var root = document.documentElement;
+  var resize = function (width) {
+    width = Math.max(200, Math.min(width, window.innerWidth - 300));
+    root.style.setProperty("--docs-width", width + "px");
+    return width;
+  };
+  try {
+    var saved = parseInt(localStorage.getItem(key), 10);
+    if (saved) {
+      resize(saved);
+    }
+  } catch (e) {}
+  if (document.body.classList.contains("linear")) {
+    return;
+  }
+  var splitter = document.createElement("div");
+  splitter.id = "splitter";
+  splitter.title = messages.resize;
+  document.body.appendChild(splitter);
+  splitter.onmousedown = function (down) {
+    down.preventDefault();
+    splitter.className = "dragging";
+    var width;
+    document.onmousemove = function (move) {
+      var x = document.dir === "rtl" ? window.innerWidth - move.clientX : move.clientX;
+      width = resize(x - 75);
+    };
+    document.onmouseup = function () {
+      document.onmousemove = document.onmouseup = null;
+      splitter.className = "";
+      try {
+        if (width) {
+          localStorage.setItem(key, width);
+        }
+      } catch (e) {}
+    };
+  };
+})();
+
+// find code tokens on the page: the box in the header marks the
+// highlighted tokens containing what is typed, Enter and Shift+Enter move
+// between them, unfolding folded code on the way, and Escape clears it
+(function () {
+  var header = document.getElementById("header");
+  var tokens = document.querySelectorAll("td.code pre span");
+  if (!header || !tokens.length) {
+    return;
+  }
+  var box = document.createElement("span");
+  box.className = "find";
+  var input = document.createElement("input");
+  input.id = "find";
+  input.type = "search";
+  input.placeholder = messages.find;
+  var count = document.createElement("span");
+  box.appendChild(input);
+  box.appendChild(count);
+  header.appendChild(box);
+
+  var found = [];
+  var at = -1;
+  var show = function () {
+    count.textContent = found.length ?
+      " " + messages.matches.replace("{current}", at + 1).replace("{total}", found.length) : "";
+  };
+  var mark = function () {
+    for (var i = 0; i < found.length; i++) {
+      found[i].classList.remove("found", "current");
+    }
+    found = [];
+    at = -1;
+    var query = input.value.trim().toLowerCase();
+    if (query) {
+      for (var i = 0; i < tokens.length; i++) {
+        if (!tokens[i].children.length && tokens[i].textContent.toLowerCase().indexOf(query) >= 0) {
+          tokens[i].classList.add("found");
+          found.push(tokens[i]);
+        }
+      }
+    }
+    show();
+  };
+  var go = function (step) {
+    if (!found.length) {
+      return;
+    }
+    if (at >= 0) {
+      found[at].classList.remove("current");
+    }
+    at = (at + step + found.length) % found.length;
+    var token = found[at];
+    var cell = token.closest("td.code");
+    if (cell && cell.classList.contains("collapsed")) {
+      cell.querySelector(".fold").click();
+    }
+    token.classList.add("current");
+    token.scrollIntoView({block: "center"});
+    show();
+  };
+  input.addEventListener("input", mark);
+  input.addEventListener("keydown", function (event) {
+    if (event.key === "Enter") {
+      event.preventDefault();
+      go(event.shiftKey ? -1 : 1);
+    } else if (event.key === "Escape") {
+      input.value = "";
+      mark();
+      input.blur();
+    }
+  });
+})();
+
+// keyboard navigation: j/k move between sections, [ and ] between
+// files, / focuses the search box when the page has one and f the box
+// finding code
+(function () {
+  var rows = document.querySelectorAll("tr[id^=section-]");
+  var current = function () {
+    var index = -1;
+    for (var i = 0; i < rows.length; i++) {
+      // rows scrolled to sit just below the sticky header count as current
+      var margin = parseInt(getComputedStyle(rows[i]).scrollMarginTop, 10) || 0;
+      if (rows[i].getBoundingClientRect().top <= margin + 1) {
+        index = i;
+      }
+    }
+    return index;
+  };
+  var follow = function (rel) {
+    var link = document.querySelector("link[rel=" + rel + "]");
+    if (link) {
+      window.location.href = link.href;
+    }
+  };
+  document.addEventListener("keydown", function (event) {
+    var target = event.target.tagName;
+    if (event.ctrlKey || event.metaKey || event.altKey ||
+        target === "INPUT" || target === "TEXTAREA" || target === "SELECT") {
+      return;
+    }
+    var index;
+    switch (event.key) {
+    case "j":
+      index = Math.min(current() + 1, rows.length - 1);
+      break;
+    case "k":
+      index = Math.max(current() - 1, 0);
+      break;
+    case "[":
+      follow("prev");
+      return;
+    case "]":
+      follow("next");
+      return;
+    case "/":
+      var search = document.getElementById("search");
+      if (search) {
+        event.preventDefault();
+        search.focus();
+      }
+      return;
+    case "f":
+      var find = document.getElementById("find");
+      if (find) {
+        event.preventDefault();
+        find.focus();
+      }
+      return;
+    default:
+      return;
+    }
+    if (rows[index]) {
+      rows[index].scrollIntoView();
+    }
+  });
+})();
+
+// following a heading's anchor also copies its address
+(function () {
+  var anchors = document.querySelectorAll(".docs .anchor");
+  for (var i = 0; i < anchors.length; i++) {
+    anchors[i].addEventListener("click", function () {
+      if (navigator.clipboard) {
+        navigator.clipboard.writeText(this.href);
+      }
+    });
+  }
+})();
+
+// links to a section by a name it no longer has are sent on to its
+// current name, see anchors.go
+(function () {
+  var data = document.getElementById("gocco-redirects");
+  if (!data) {
+    return;
+  }
+  var redirects = JSON.parse(data.textContent);
+  var follow = function () {
+    var name = decodeURIComponent(window.location.hash.slice(1));
+    if (name && !document.getElementById(name) && redirects[name]) {
+      window.location.replace("#" + encodeURIComponent(redirects[name]));
+    }
+  };
+  window.addEventListener("hashchange", follow);
+  follow();
+})();
+
+// briefly highlight the section a link points into, whether it targets
+// the section itself or a heading inside it
+(function () {
+  var flash = function () {
+    var target = window.location.hash &&
+      document.getElementById(decodeURIComponent(window.location.hash.slice(1)));
+    while (target && target.tagName !== "TR") {
+      target = target.parentNode;
+    }
+    if (!target) {
+      return;
+    }
+    target.classList.remove("targeted");
+    // restart the animation when the same section is targeted again
+    void target.offsetWidth;
+    target.classList.add("targeted");
+  };
+  window.addEventListener("hashchange", flash);
+  flash();
+})();
+`
+
 var HTML = `
 <!DOCTYPE html>
 
-<html>
+<html lang="{{ lang }}" dir="{{ .Direction }}">
 <head>
-    <title>{{ .Title }}</title>
+    <title>{{ .Title | html }}{{ if .SiteTitle }} &ndash; {{ .SiteTitle | html }}{{ end }}</title>
   <meta http-equiv="content-type" content="text/html; charset=UTF-8">
-  <link rel="stylesheet" media="all" href="gocco.css" />
+  {{ if .Description }}<meta name="description" content="{{ .Description | html }}" />{{ end }}
+  {{ if .Draft }}<meta name="robots" content="noindex" />{{ end }}
+  {{ if .InlineCSS }}<style>{{ .InlineCSS }}</style>{{ else }}<link rel="stylesheet" media="all" href="{{ .Root }}{{ asset "gocco.css" }}" />{{ end }}
+  {{ if .Canonical }}<link rel="canonical" href="{{ .Canonical }}" />{{ end }}
+  {{ if .SocialCard }}
+  <meta property="og:type" content="article" />
+  <meta property="og:title" content="{{ .Title | html }}" />
+  <meta property="og:site_name" content="{{ .SiteName | html }}" />
+  {{ if .Description }}<meta property="og:description" content="{{ .Description | html }}" />{{ end }}
+  <meta property="og:url" content="{{ .Canonical }}" />
+  <meta property="og:image" content="{{ .SocialCard }}" />
+  <meta name="twitter:card" content="summary_large_image" />
+  {{ end }}
+  {{ .Analytics }}
+  {{ if .OpenSearch }}<link rel="search" type="application/opensearchdescription+xml" title="{{ .SiteName | html }}" href="{{ .OpenSearch }}" />{{ end }}
+  {{ if .Style }}
+  {{ if .CSP }}
+  <link rel="stylesheet" media="all" href="{{ .Root }}{{ styleFile .Style }}" />
+  {{ else }}
+  <style>{{ .StyleSheet }}</style>
+  {{ end }}
+  {{ end }}
+  {{ if .HighlightStyle }}<link rel="stylesheet" media="all" href="{{ .HighlightStyle }}" />{{ end }}
+  {{ if .Prev }}<link rel="prev" href="{{ .Prev }}" />{{ end }}
+  {{ if .Next }}<link rel="next" href="{{ .Next }}" />{{ end }}
 </head>
-<body>
+<body class="{{ .Layout }}{{ if .StickyDocs }} sticky-docs{{ end }}">
   <div id="container">
     <div id="background"></div>
-    {{ if .Multiple }}
-      <div id="jump_to">
-        Jump To &hellip;
-        <div id="jump_wrapper">
-          <div id="jump_page">
-              {{ range .Sources }}
-              <a class="source" href="{{ destination . | base }}">
-                  {{ base . }}
-              </a>
-              {{ end }}
+    <div id="header" role="banner">
+      {{ if .SiteTitle }}<span class="site">{{ .SiteTitle | html }}</span>{{ end }}
+      <span class="path">{{ .Path | html }}</span>
+      {{ if .Index }}<a class="index" href="{{ .Root }}{{ .Index }}">{{ t "index" }}</a>{{ end }}
+      {{ if .Raw }}<a class="raw" href="{{ .Raw }}" download>{{ t "raw" }}</a>{{ end }}
+      {{ if .Search }}
+      <form class="search" action="{{ .Root }}search.html">
+        <input id="search" type="search" name="q" placeholder="{{ t "search" }}" />
+      </form>
+      {{ end }}
+      {{ if .Multiple }}
+        <div id="jump_to">
+          {{ t "jump_to" }}
+          <div id="jump_wrapper">
+            <div id="jump_page">
+                {{ range .Jump }}
+                {{ if .Part }}<div class="part">{{ .Part | html }}</div>{{ end }}
+                <a class="source" href="{{ .Href | html }}">
+                    {{ .Name | html }}
+                </a>
+                {{ end }}
+            </div>
           </div>
         </div>
-      </div>
-    {{ end }}
+      {{ end }}
+    </div>
+    <main>
     <table cellpadding="0" cellspacing="0">
       <thead>
         <tr>
           <th class="docs">
             <h1>
-                {{ .Title }}
+                {{ .Title | html }}
             </h1>
           </th>
           <th class="code">
@@ -228,22 +915,706 @@ var HTML = `
         </tr>
       </thead>
       <tbody>
+          {{ with .Files }}
+          <tr class="files">
+            <td class="docs">
+              <h2>{{ t "files" }}</h2>
+              <ol class="chapters">
+                {{ range . }}<li><a href="{{ .Href | html }}">{{ .Name | html }}</a></li>{{ end }}
+              </ol>
+            </td>
+            <td class="code">
+            </td>
+          </tr>
+          {{ end }}
           {{ range .Sections }}
-          <tr id="section-{{ .Index }}">
+          {{ if .File }}
+          <tr class="file" id="{{ .FileAnchor }}">
             <td class="docs">
-              <div class="pilwrap">
-                  <a class="pilcrow" href="#section-{{ .Index }}">&#182;</a>
-              </div>
-                {{ .DocsHTML }}
+              <h2 class="file">{{ .File | html }}</h2>
             </td>
             <td class="code">
+            </td>
+          </tr>
+          {{ end }}
+          <tr id="{{ .ID }}" data-lines="{{ .StartLine }}-{{ .EndLine }}">
+            <td class="docs{{ if .Recaps }} balanced{{ end }}">
+              <div class="section-docs">
+                {{ if and .Anchor (not .AnchorHeading) }}<span class="section-anchor" id="{{ .Anchor }}"></span>{{ end }}
+                <div class="pilwrap">
+                    <a class="pilcrow" href="#{{ or .Anchor .ID }}">&#182;</a>
+                </div>
+                  {{ .DocsHTML }}
+              </div>
+              {{ range .Recaps }}<div class="recap" style="top: {{ .Top }}px" aria-hidden="true">{{ .Text | html }}</div>{{ end }}
+            </td>
+            <td class="code"{{ if .Collapsed }} data-collapsed{{ end }}>
                 {{ .CodeHTML }}
             </td>
           </tr>
           {{ end }}
       </tbody>
     </table>
+    </main>
+    {{ if .GeneratedAt }}<div id="footer">{{ .GeneratedAt }}</div>{{ end }}
   </div>
+  <script type="application/json" id="gocco-messages">{{ messages }}</script>
+  {{ with .Redirects }}<script type="application/json" id="gocco-redirects">{{ . }}</script>{{ end }}
+  {{ if .HighlightScript }}<script src="{{ .HighlightScript }}"></script>{{ end }}
+  {{ if .CSP }}
+  <script src="{{ .Root }}{{ asset "gocco.js" }}"></script>
+  {{ else }}
+  <script>{{ js }}</script>
+  {{ end }}
 </body>
 </html>
 `
+
+// ContentsHTML is the table of contents of a book, its `index.html`
+var ContentsHTML = `
+<!DOCTYPE html>
+
+<html lang="{{ lang }}">
+<head>
+  <title>{{ .Title | html }}</title>
+  <meta http-equiv="content-type" content="text/html; charset=UTF-8">
+  <link rel="stylesheet" media="all" href="{{ asset "gocco.css" }}" />
+  {{ .Analytics }}
+</head>
+<body>
+  <div id="container">
+    <div id="header" role="banner">
+      <span class="site">{{ .Title | html }}</span>
+    </div>
+    <main>
+    <table cellpadding="0" cellspacing="0">
+      <thead>
+        <tr>
+          <th class="docs">
+            <h1>{{ .Title | html }}</h1>
+          </th>
+        </tr>
+      </thead>
+      <tbody>
+        <tr>
+          <td class="docs">
+            {{ with .Readme }}<div class="readme">{{ . }}</div>{{ end }}
+            {{ range .Parts }}
+            {{ if .Title }}<h2>{{ .Title | html }}</h2>{{ end }}
+            <ol class="chapters">
+              {{ range .Chapters }}
+              <li><a href="{{ page . }}">{{ base . }}</a></li>
+              {{ end }}
+            </ol>
+            {{ end }}
+          </td>
+        </tr>
+      </tbody>
+    </table>
+    </main>
+  </div>
+</body>
+</html>
+`
+
+// OverviewHTML is the page of a Go package's `doc.go`, its package
+// comment across the whole width followed by the other files
+var OverviewHTML = `
+<!DOCTYPE html>
+
+<html lang="{{ lang }}">
+<head>
+  <title>{{ if .SiteTitle }}{{ .SiteTitle | html }} – {{ end }}package {{ .Package }}</title>
+  <meta http-equiv="content-type" content="text/html; charset=UTF-8">
+  <link rel="stylesheet" media="all" href="{{ .Root }}{{ asset "gocco.css" }}" />
+  {{ .Analytics }}
+</head>
+<body>
+  <div id="container">
+    <div id="header" role="banner">
+      {{ if .SiteTitle }}<span class="site">{{ .SiteTitle | html }}</span>{{ end }}
+      <span class="path">{{ .Path | html }}</span>
+      {{ if .Index }}<a class="index" href="{{ .Root }}{{ .Index }}">{{ t "index" }}</a>{{ end }}
+    </div>
+    <main>
+    <table cellpadding="0" cellspacing="0">
+      <thead>
+        <tr>
+          <th class="docs overview">
+            <h1>package {{ .Package }}</h1>
+          </th>
+        </tr>
+      </thead>
+      <tbody>
+        <tr>
+          <td class="docs overview">
+            {{ with .Readme }}<div class="readme">{{ . }}</div>{{ end }}
+            {{ .Overview }}
+            {{ if .Files }}
+            {{ if .API }}<p><a href="{{ .API }}">{{ t "api" }}</a></p>{{ end }}
+            <h2>{{ t "files" }}</h2>
+            <ul class="files">
+              {{ range .Files }}
+              <li><a href="{{ .Href | html }}">{{ .Name | html }}</a></li>
+              {{ end }}
+            </ul>
+            {{ end }}
+          </td>
+        </tr>
+      </tbody>
+    </table>
+    </main>
+  </div>
+</body>
+</html>
+`
+
+// APIHTML is the reference page of a Go package
+var APIHTML = `
+<!DOCTYPE html>
+
+<html lang="{{ lang }}">
+<head>
+  <title>{{ if .SiteTitle }}{{ .SiteTitle | html }} – {{ end }}package {{ .Package.Name }}</title>
+  <meta http-equiv="content-type" content="text/html; charset=UTF-8">
+  <link rel="stylesheet" media="all" href="{{ .Root }}{{ asset "gocco.css" }}" />
+  {{ .Analytics }}
+</head>
+<body>
+  <div id="container">
+    <div id="header" role="banner">
+      {{ if .SiteTitle }}<span class="site">{{ .SiteTitle | html }}</span>{{ end }}
+      <span class="path">{{ .Package.Dir | html }}</span>
+      {{ if .Index }}<a class="index" href="{{ .Root }}{{ .Index }}">{{ t "index" }}</a>{{ end }}
+    </div>
+    <main>
+    <table cellpadding="0" cellspacing="0">
+      <thead>
+        <tr>
+          <th class="docs overview">
+            <h1>package {{ .Package.Name }}</h1>
+          </th>
+        </tr>
+      </thead>
+      <tbody>
+        <tr>
+          <td class="docs overview api">
+            {{ with .Package.Constants }}<h2>{{ t "constants" }}</h2>{{ template "entries" . }}{{ end }}
+            {{ with .Package.Variables }}<h2>{{ t "variables" }}</h2>{{ template "entries" . }}{{ end }}
+            {{ with .Package.Functions }}<h2>{{ t "functions" }}</h2>{{ template "entries" . }}{{ end }}
+            {{ with .Package.Types }}<h2>{{ t "types" }}</h2>{{ template "entries" . }}{{ end }}
+          </td>
+        </tr>
+      </tbody>
+    </table>
+    </main>
+  </div>
+</body>
+</html>
+{{ define "entries" }}
+{{ range . }}
+<div class="entry">
+  <h3 id="{{ .Name }}"><a href="{{ .Href }}">{{ .Name }}</a></h3>
+  <pre><code>{{ .Signature | html }}</code></pre>
+  {{ .Doc }}
+  {{ with .Funcs }}<div class="funcs">{{ template "entries" . }}</div>{{ end }}
+</div>
+{{ end }}
+{{ end }}
+`
+
+// HeatmapHTML is the treemap of comment density written with `-heatmap`.
+// Directories alternate between laying out their children in rows and
+// in columns
+var HeatmapHTML = `
+<!DOCTYPE html>
+
+<html lang="{{ lang }}">
+<head>
+  <title>{{ .Title | html }} – heat map</title>
+  <meta http-equiv="content-type" content="text/html; charset=UTF-8">
+  <link rel="stylesheet" media="all" href="{{ asset "gocco.css" }}" />
+  <style>
+    .heatmap { display: flex; height: 80vh; margin: 10px 25px; font-size: 12px; }
+    .heatmap .dir, .heatmap .file { display: flex; flex-basis: 0; min-width: 0; min-height: 0; overflow: hidden; }
+    .heatmap .dir { flex-direction: column; border: 1px solid #999; margin: 1px; }
+    .heatmap .dir > .children { display: flex; flex: 1; }
+    .heatmap .columns > .children { flex-direction: column; }
+    .heatmap .dir > .name { padding: 1px 4px; font-weight: bold; white-space: nowrap; }
+    .heatmap .file { padding: 2px 4px; border: 1px solid white; color: #252519; text-decoration: none; word-break: break-all; }
+    .heatmap .file:hover { border-color: #252519; }
+  </style>
+  {{ .Analytics }}
+</head>
+<body>
+  <div id="container">
+    <div id="header" role="banner">
+      <span class="site">{{ .Title | html }}</span>
+      {{ if .Index }}<a class="index" href="{{ .Index }}">{{ t "index" }}</a>{{ end }}
+    </div>
+    <main>
+      <div class="heatmap">{{ template "node" .Root }}</div>
+    </main>
+  </div>
+</body>
+</html>
+{{ define "node" }}
+{{ if .Children }}
+<div class="dir{{ if .Columns }} columns{{ end }}" style="flex-grow: {{ .Lines }}; background: {{ .Color }}">
+  <span class="name">{{ .Name | html }} {{ .Density }}%</span>
+  <div class="children">{{ range .Children }}{{ template "node" . }}{{ end }}</div>
+</div>
+{{ else }}
+<a class="file" href="{{ .Href | html }}" style="flex-grow: {{ .Lines }}; background: {{ .Color }}" title="{{ .Name | html }}: {{ .Density }}% of {{ .Lines }} lines are comments">{{ .Name | html }}</a>
+{{ end }}
+{{ end }}
+`
+
+// StatsHTML is the table of lines, sections and coverage written with
+// `-stats`. Every cell carries the value it sorts by
+var StatsHTML = `
+<!DOCTYPE html>
+
+<html lang="{{ lang }}">
+<head>
+  <title>{{ .Title | html }} – stats</title>
+  <meta http-equiv="content-type" content="text/html; charset=UTF-8">
+  <link rel="stylesheet" media="all" href="{{ asset "gocco.css" }}" />
+  <style>
+    table.stats { margin: 10px 25px; border-collapse: collapse; font-size: 14px; }
+    table.stats th, table.stats td { padding: 4px 12px; border-bottom: 1px solid #e5e5ee; text-align: right; }
+    table.stats th:first-child, table.stats td:first-child { text-align: left; }
+    table.stats th { cursor: pointer; user-select: none; white-space: nowrap; }
+    table.stats th[aria-sort=ascending]::after { content: " \25B2"; }
+    table.stats th[aria-sort=descending]::after { content: " \25BC"; }
+    table.stats tfoot td { font-weight: bold; border-bottom: 0; }
+  </style>
+  {{ .Analytics }}
+</head>
+<body>
+  <div id="container">
+    <div id="header" role="banner">
+      <span class="site">{{ .Title | html }}</span>
+      {{ if .Index }}<a class="index" href="{{ .Index }}">{{ t "index" }}</a>{{ end }}
+    </div>
+    <main>
+      <table class="stats">
+        <thead>
+          <tr>
+            <th data-type="text">File</th>
+            <th>Code lines</th>
+            <th>Doc lines</th>
+            <th>Docs</th>
+            <th>Sections</th>
+            <th>Last modified</th>
+            {{ if .Coverage }}<th>Coverage</th>{{ end }}
+          </tr>
+        </thead>
+        <tbody>
+          {{ $coverage := .Coverage }}
+          {{ range .Files }}
+          <tr>
+            <td data-value="{{ .Source | html }}"><a href="{{ .Href | html }}">{{ .Source | html }}</a></td>
+            <td data-value="{{ .Code }}">{{ .Code }}</td>
+            <td data-value="{{ .Docs }}">{{ .Docs }}</td>
+            <td data-value="{{ .Density }}">{{ .Density }}%</td>
+            <td data-value="{{ .Sections }}">{{ .Sections }}</td>
+            <td data-value="{{ .Modified.Unix }}">{{ .Modified.Format "2006-01-02 15:04" }}</td>
+            {{ if $coverage }}<td data-value="{{ .Coverage }}">{{ .CoverageText }}</td>{{ end }}
+          </tr>
+          {{ end }}
+        </tbody>
+        <tfoot>
+          {{ with .Total }}
+          <tr>
+            <td>{{ len $.Files }} files</td>
+            <td>{{ .Code }}</td>
+            <td>{{ .Docs }}</td>
+            <td>{{ .Density }}%</td>
+            <td>{{ .Sections }}</td>
+            <td>{{ .Modified.Format "2006-01-02 15:04" }}</td>
+            {{ if $coverage }}<td>{{ .CoverageText }}</td>{{ end }}
+          </tr>
+          {{ end }}
+        </tfoot>
+      </table>
+    </main>
+  </div>
+  {{ if .CSP }}
+  <script src="{{ asset "gocco-stats.js" }}"></script>
+  {{ else }}
+  <script>{{ statsJs }}</script>
+  {{ end }}
+</body>
+</html>
+`
+
+// StatsJs sorts the table of the stats page by the column whose heading
+// is clicked, the other way round when clicked again. Numbers start out
+// biggest first
+var StatsJs = `
+(function () {
+  var table = document.querySelector("table.stats");
+  var headings = table.querySelectorAll("thead th");
+  var body = table.querySelector("tbody");
+  for (var i = 0; i < headings.length; i++) {
+    headings[i].onclick = (function (column, heading) {
+      return function () {
+        var text = heading.getAttribute("data-type") === "text";
+        var ascending = heading.getAttribute("aria-sort") ?
+          heading.getAttribute("aria-sort") === "descending" : text;
+        var rows = Array.prototype.slice.call(body.rows);
+        rows.sort(function (a, b) {
+          var x = a.cells[column].getAttribute("data-value");
+          var y = b.cells[column].getAttribute("data-value");
+          var order = text ? x.localeCompare(y) : Number(x) - Number(y);
+          return ascending ? order : -order;
+        });
+        for (var j = 0; j < rows.length; j++) {
+          body.appendChild(rows[j]);
+        }
+        for (var k = 0; k < headings.length; k++) {
+          headings[k].removeAttribute("aria-sort");
+        }
+        heading.setAttribute("aria-sort", ascending ? "ascending" : "descending");
+      };
+    })(i, headings[i]);
+  }
+})();
+`
+
+// ChangesHTML lists the pages that changed between two builds, written
+// by `gocco diff-output -html`
+var ChangesHTML = `
+<!DOCTYPE html>
+
+<html lang="{{ lang }}">
+<head>
+  <title>{{ .Title | html }} – changes</title>
+  <meta http-equiv="content-type" content="text/html; charset=UTF-8">
+  <link rel="stylesheet" media="all" href="{{ asset "gocco.css" }}" />
+</head>
+<body>
+  <div id="container">
+    <div id="header" role="banner">
+      <span class="site">{{ .Title | html }}</span>
+    </div>
+    <main>
+    <table cellpadding="0" cellspacing="0">
+      <thead>
+        <tr>
+          <th class="docs">
+            <h1>Changes since the last build</h1>
+          </th>
+        </tr>
+      </thead>
+      <tbody>
+        <tr>
+          <td class="docs">
+            {{ if not (or .Added .Changed .Removed) }}<p>No pages changed.</p>{{ end }}
+            {{ with .Added }}<h2>Added</h2>
+            <ul>{{ range . }}<li><a href="{{ .Path | html }}">{{ .Path | html }}</a>{{ with .Source }} from <code>{{ . | html }}</code>{{ end }}</li>{{ end }}</ul>{{ end }}
+            {{ with .Changed }}<h2>Changed</h2>
+            <ul>{{ range . }}<li><a href="{{ .Path | html }}">{{ .Path | html }}</a>{{ with .Source }} from <code>{{ . | html }}</code>{{ end }}</li>{{ end }}</ul>{{ end }}
+            {{ with .Removed }}<h2>Removed</h2>
+            <ul>{{ range . }}<li>{{ .Path | html }}{{ with .Source }} from <code>{{ . | html }}</code>{{ end }}</li>{{ end }}</ul>{{ end }}
+          </td>
+        </tr>
+      </tbody>
+    </table>
+    </main>
+  </div>
+</body>
+</html>
+`
+
+// FragmentHTML is the bare sections of a source, without a page around
+// them, as `-editor-server` returns them for a preview
+var FragmentHTML = `
+{{ range . }}<div class="section" id="{{ .ID }}" data-lines="{{ .StartLine }}-{{ .EndLine }}">
+  <div class="docs">{{ .DocsHTML }}</div>
+  <div class="code">{{ .CodeHTML }}</div>
+</div>
+{{ end }}`
+
+// NotFoundHTML is the 404 page written for sites with a base URL. It may
+// be served from any path, so every link is absolute
+var NotFoundHTML = `
+<!DOCTYPE html>
+
+<html lang="{{ lang }}">
+<head>
+  <title>{{ t "not_found" }}{{ if .SiteTitle }} &ndash; {{ .SiteTitle | html }}{{ end }}</title>
+  <meta http-equiv="content-type" content="text/html; charset=UTF-8">
+  <link rel="stylesheet" media="all" href="{{ .BaseURL }}/{{ asset "gocco.css" }}" />
+  {{ .Analytics }}
+</head>
+<body>
+  <div id="container">
+    <div id="header" role="banner">
+      <span class="path">404</span>
+    </div>
+    <main>
+    <table cellpadding="0" cellspacing="0">
+      <thead>
+        <tr>
+          <th class="docs">
+            <h1>{{ t "not_found" }}</h1>
+          </th>
+        </tr>
+      </thead>
+      <tbody>
+        <tr>
+          <td class="docs">
+            <p>{{ t "not_found_text" }}</p>
+            <ul>
+              {{ range .Sources }}
+              <li><a href="{{ $.BaseURL }}/{{ page . }}">{{ base . }}</a></li>
+              {{ end }}
+            </ul>
+          </td>
+        </tr>
+      </tbody>
+    </table>
+    </main>
+  </div>
+</body>
+</html>
+`
+
+// SearchHTML is the page `-search` adds, which runs `SearchJs` over the
+// index in `search-index.js`
+var SearchHTML = `
+<!DOCTYPE html>
+
+<html lang="{{ lang }}">
+<head>
+  <title>{{ t "search" }}{{ if .SiteTitle }} &ndash; {{ .SiteTitle | html }}{{ end }}</title>
+  <meta http-equiv="content-type" content="text/html; charset=UTF-8">
+  <link rel="stylesheet" media="all" href="{{ asset "gocco.css" }}" />
+  {{ .Analytics }}
+  {{ if .OpenSearch }}<link rel="search" type="application/opensearchdescription+xml" title="{{ .SiteName | html }}" href="{{ .OpenSearch }}" />{{ end }}
+</head>
+<body>
+  <div id="container">
+    <div id="header" role="banner">
+      <span class="path">search</span>
+      <form class="search" action="search.html">
+        <input id="search" type="search" name="q" placeholder="{{ t "search" }}" />
+      </form>
+    </div>
+    <main>
+    <table cellpadding="0" cellspacing="0">
+      <thead>
+        <tr>
+          <th class="docs">
+            <h1>{{ t "search" }}</h1>
+          </th>
+        </tr>
+      </thead>
+      <tbody>
+        <tr>
+          <td class="docs">
+            <ul id="results"></ul>
+          </td>
+        </tr>
+      </tbody>
+    </table>
+    </main>
+  </div>
+  <script type="application/json" id="gocco-messages">{{ messages }}</script>
+  <script src="{{ asset "search-index.js" }}"></script>
+  {{ if .CSP }}
+  <script src="{{ asset "gocco-search.js" }}"></script>
+  {{ else }}
+  <script>{{ searchJs }}</script>
+  {{ end }}
+</body>
+</html>
+`
+
+// SearchJs looks up the words of the query in the search index, listing
+// the sections whose docs contain all of them
+var SearchJs = `
+(function () {
+  var messages = JSON.parse(document.getElementById("gocco-messages").textContent);
+  var query = new URLSearchParams(window.location.search).get("q") || "";
+  var input = document.getElementById("search");
+  var results = document.getElementById("results");
+  input.value = query;
+  input.focus();
+  var terms = query.toLowerCase().split(/\s+/).filter(Boolean);
+  if (!terms.length) {
+    return;
+  }
+  var found = goccoSearchIndex.filter(function (entry) {
+    var text = (entry.title + " " + entry.text).toLowerCase();
+    return terms.every(function (term) { return text.indexOf(term) >= 0; });
+  });
+  if (!found.length) {
+    results.textContent = messages.no_results.replace("{query}", query);
+    return;
+  }
+  found.forEach(function (entry) {
+    var item = document.createElement("li");
+    var link = document.createElement("a");
+    link.href = entry.page + "#" + entry.anchor;
+    link.textContent = entry.title;
+    var excerpt = document.createElement("div");
+    var at = Math.max(entry.text.toLowerCase().indexOf(terms[0]) - 60, 0);
+    excerpt.textContent = (at > 0 ? "\u2026" : "") + entry.text.substr(at, 200) + "\u2026";
+    item.appendChild(link);
+    item.appendChild(excerpt);
+    results.appendChild(item);
+  });
+})();
+`
+
+// OpenSearchXML describes the search page to browsers
+var OpenSearchXML = `<?xml version="1.0" encoding="UTF-8"?>
+<OpenSearchDescription xmlns="http://a9.com/-/spec/opensearch/1.1/">
+  <ShortName>{{ .Name }}</ShortName>
+  <Description>Search {{ .Name }}</Description>
+  <InputEncoding>UTF-8</InputEncoding>
+  <Url type="text/html" method="get" template="{{ .SearchURL }}?q={searchTerms}"/>
+</OpenSearchDescription>
+`
+
+// StylesHTML is the page `gocco styles -preview` renders
+var StylesHTML = `
+<!DOCTYPE html>
+
+<html>
+<head>
+  <title>Styles</title>
+  <meta http-equiv="content-type" content="text/html; charset=UTF-8">
+  <style>
+    body {
+      font-family: 'Palatino Linotype', 'Book Antiqua', Palatino, FreeSerif, serif;
+      font-size: 15px;
+      color: #252519;
+      margin: 40px 50px;
+    }
+    .style {
+      display: inline-block;
+      vertical-align: top;
+      margin: 0 20px 30px 0;
+      width: 560px;
+    }
+      .style h2 {
+        font-size: 16px;
+        margin: 0 0 5px 0;
+      }
+      .style .highlight {
+        padding: 10px 15px;
+        border: 1px solid #e5e5ee;
+        overflow-x: auto;
+      }
+    pre {
+      font-size: 12px; line-height: 18px;
+      font-family: Menlo, Monaco, Consolas, "Lucida Console", monospace;
+      margin: 0;
+    }
+    {{ range .Styles }}{{ .StyleSheet }}{{ end }}
+  </style>
+</head>
+<body>
+  <h1>Styles</h1>
+  <p>{{ .Source }} in every available style, use one with <code>style:</code> in a file's front matter.</p>
+  {{ range .Styles }}
+  <div class="style" id="style-{{ .Name }}">
+    <h2>{{ .Name }}</h2>
+    {{ $.Code }}
+  </div>
+  {{ end }}
+</body>
+</html>
+`
+
+// SampleSource is rendered by the style gallery when no file is given
+var SampleSource = `package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// greeting is printed once per name
+const greeting = "Hello, %s!\n"
+
+type Greeter struct {
+	Names []string
+	count int
+}
+
+/* Greet prints a greeting for
+   every name it knows */
+func (g *Greeter) Greet() (n int, err error) {
+	for i, name := range g.Names {
+		if strings.TrimSpace(name) == "" {
+			return i, fmt.Errorf("empty name at %d", i)
+		}
+		fmt.Printf(greeting, name)
+		g.count += 1 << 2
+	}
+	return len(g.Names), nil
+}
+
+func main() {
+	g := &Greeter{Names: []string{"gopher", "docco"}}
+	g.Greet()
+}
+`
+
+// SampleProse is a source heavy on documentation, rendered along with
+// `SampleSource` by `gocco verify-theme`
+var SampleProse = `// # Word counts
+//
+// Counting words is the *hello world* of text processing. This one reads
+// standard input and prints the most common words:
+//
+// 1. split the text on whitespace
+// 2. fold case, so **The** and **the** count once
+// 3. print the ` + "`top`" + ` words
+//
+// > Punctuation is left alone, on purpose.
+package gocco
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ## Counting
+//
+// A map does the bookkeeping, see the
+// [spec](https://go.dev/ref/spec#Map_types).
+func count(words []string, top int) []string {
+	counts := make(map[string]int)
+	for _, w := range words {
+		counts[strings.ToLower(w)]++
+	}
+	var sorted []string
+	for w := range counts {
+		sorted = append(sorted, w)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return counts[sorted[i]] > counts[sorted[j]] })
+	if len(sorted) > top {
+		sorted = sorted[:top]
+	}
+	return sorted
+}
+
+func main() {
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Split(bufio.ScanWords)
+	var words []string
+	for scanner.Scan() {
+		words = append(words, scanner.Text())
+	}
+	fmt.Println(count(words, 10))
+}
+`
@@ -0,0 +1,84 @@
+package gocco
+
+import (
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// ## Large inputs
+
+// A goroutine per source is fine for a package, but a monorepo has tens
+// of thousands of files, and every file in flight holds its source, its
+// sections and a pipe to Pygments. Only `-jobs` files are worked on at
+// once, which also keeps the number of open file descriptors well below
+// the usual limits
+var jobs = runtime.NumCPU()
+
+// Above `-menu-limit` sources, listing every file in the jump menu of
+// every page makes the docs grow with the square of their number. The
+// menu is sharded instead: each page lists the files next to it, and the
+// full list goes to the index page
+var menuLimit = 500
+
+// whether the jump menu is too long to put on every page
+func (g *Generator) shardedMenu() bool {
+	return joinName == "" && menuLimit > 0 && len(g.Sources) > menuLimit
+}
+
+// `forEachSource` runs `work` on every source, handing them to a fixed
+// number of workers as they become free
+func forEachSource(work func(i int, source string)) {
+	n := jobs
+	if n < 1 {
+		n = 1
+	}
+	next := make(chan int)
+	wg := new(sync.WaitGroup)
+	wg.Add(n)
+	for w := 0; w < n; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				work(i, sources[i])
+			}
+		}()
+	}
+	for i := range sources {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
+
+// the jump menu of the page of `source`
+func (g *Generator) jumpLinks(source string) []*JumpLink {
+	if !g.shardedMenu() {
+		var jump []*JumpLink
+		// a part starting at a draft starts at the next listed file
+		pending := ""
+		for i, s := range g.Sources {
+			part := partStarting(s)
+			if book == nil {
+				part = g.packageStarting(i)
+			}
+			if part != "" {
+				pending = part
+			}
+			if drafts[s] {
+				continue
+			}
+			jump = append(jump, &JumpLink{filepath.Base(s), pageLink(source, s), pending})
+			pending = ""
+		}
+		return jump
+	}
+	dir := filepath.Dir(source)
+	var jump []*JumpLink
+	for _, s := range g.Sources {
+		if filepath.Dir(s) == dir && !drafts[s] {
+			jump = append(jump, &JumpLink{filepath.Base(s), pageLink(source, s), ""})
+		}
+	}
+	return jump
+}
@@ -0,0 +1,121 @@
+package gocco
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"html"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ## Search
+
+// With `-search` every page gets a search box leading to `search.html`,
+// which looks through an index of all the sections' docs in the browser.
+// The index is a script rather than JSON so that it also loads from
+// `file://` addresses
+var search bool
+
+// a `SearchEntry` is one documented section in the index
+type SearchEntry struct {
+	Page   string `json:"page"`
+	Anchor string `json:"anchor"`
+	Title  string `json:"title"`
+	Text   string `json:"text"`
+}
+
+// the index is filled by the goroutines documenting each file
+var searchIndex []*SearchEntry
+var searchIndexLock sync.Mutex
+
+// add the sections of a page to the index
+func indexForSearch(source, title string, sections *list.List) {
+	page := pagePath(source)
+	var entries []*SearchEntry
+	ids := sectionIDs(sections)
+	for e, i := sections.Front(), 0; e != nil; e, i = e.Next(), i+1 {
+		text := strings.Join(strings.Fields(string(e.Value.(*Section).docsText)), " ")
+		if text == "" {
+			continue
+		}
+		entries = append(entries, &SearchEntry{page, ids[i], title, text})
+	}
+	searchIndexLock.Lock()
+	searchIndex = append(searchIndex, entries...)
+	searchIndexLock.Unlock()
+}
+
+// write the search page and its index once every page is done
+func writeSearch() {
+	// pages finish in any order, keep the index stable between runs
+	sort.SliceStable(searchIndex, func(i, j int) bool {
+		return searchIndex[i].Page < searchIndex[j].Page
+	})
+	index, err := json.Marshal(searchIndex)
+	if err != nil {
+		log.Println("gocco: ", err)
+		return
+	}
+	script := new(bytes.Buffer)
+	script.WriteString("var goccoSearchIndex = ")
+	script.Write(index)
+	script.WriteString(";\n")
+	if err := writeAsset("search-index.js", script.Bytes()); err != nil {
+		renderFailure(err)
+		return
+	}
+	if csp {
+		if err := writeAsset("gocco-search.js", []byte(SearchJs)); err != nil {
+			renderFailure(err)
+		}
+	}
+	page, err := renderTemplate("search", SearchHTML, map[string]interface{}{
+		"CSP":        csp,
+		"Analytics":  analytics,
+		"SiteTitle":  siteTitle,
+		"SiteName":   siteName(),
+		"OpenSearch": openSearchURL(),
+	})
+	dest := filepath.Join(outputDir, "search.html")
+	if err == nil {
+		err = writeFile(dest, page)
+	}
+	if err != nil {
+		renderFailure(err)
+		return
+	}
+	log.Println("gocco: search -> ", dest)
+
+	if baseURL != "" {
+		writeOpenSearch()
+	}
+}
+
+// ### OpenSearch
+// Published sites with search also describe it with an OpenSearch
+// document, so browsers can offer it as a search engine
+
+// the address of the OpenSearch document, if there is one
+func openSearchURL() string {
+	if !search {
+		return ""
+	}
+	return siteURL("opensearch.xml")
+}
+
+func writeOpenSearch() {
+	xml, err := renderTemplate("opensearch", OpenSearchXML, map[string]interface{}{
+		"Name":      html.EscapeString(siteName()),
+		"SearchURL": html.EscapeString(siteURL("search.html")),
+	})
+	if err == nil {
+		err = writeFile(filepath.Join(outputDir, "opensearch.xml"), xml)
+	}
+	if err != nil {
+		renderFailure(err)
+	}
+}
@@ -0,0 +1,245 @@
+package gocco
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ## Serving
+
+// `gocco serve` renders pages when they're asked for instead of writing
+// them all up front, which suits a hosted renderer or a large tree of
+// which only a few files are read:
+//
+//     gocco serve -addr :8000 *.go
+//
+// A page is rendered again only once its source changed, until then it
+// comes from a cache. Everything else, like copied images, is served
+// from `docs/`.
+//
+// The sources are watched while serving, and an open page reloads itself
+// as soon as its source is saved, told so over server-sent events from
+// `/_gocco/reload`. `-reload=false` leaves the pages as they are built
+func serveCommand(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", "localhost:8000", "address to listen on")
+	port := flags.Int("port", 0, "port to listen on at localhost, instead of -addr")
+	reload := flags.Bool("reload", true, "reload open pages when their source is saved")
+	flags.Parse(args)
+	if *port != 0 {
+		*addr = fmt.Sprintf("localhost:%d", *port)
+	}
+
+	sources = collectSources(flags.Args())
+	sort.Strings(sources)
+	if len(sources) == 0 {
+		fail(exitInput, "serve: no sources to serve")
+	}
+	commandSettings()
+	if err := ensureDirectory(outputDir); err != nil {
+		fail(exitRender, err)
+	}
+
+	server := newPageServer()
+	http.Handle("/", server)
+	http.HandleFunc("/metrics", serveMetrics)
+	if *reload {
+		server.reloads = make(map[chan string]bool)
+		http.HandleFunc(reloadPath, server.serveReloads)
+		go func() {
+			if err := watchFiles(sources, server.reloadPages); err != nil {
+				log.Println("gocco: serve: not reloading pages: ", err)
+			}
+		}()
+	}
+	log.Println("gocco: serving ", len(sources), " sources on http://", *addr)
+	if err := http.ListenAndServe(*addr, nil); err != nil {
+		fail(exitConfig, err)
+	}
+}
+
+// a `cachedPage` is a rendered page and the time its source was changed
+// when it was rendered
+type cachedPage struct {
+	html    []byte
+	modTime time.Time
+}
+
+// a `pageServer` renders the pages of the sources by their path
+type pageServer struct {
+	pages     map[string]string
+	cache     map[string]*cachedPage
+	cacheLock sync.Mutex
+	files     http.Handler
+	// the pages open in a browser, each waiting for the paths of pages
+	// to reload, nil without `-reload`
+	reloads     map[chan string]bool
+	reloadsLock sync.Mutex
+}
+
+func newPageServer() *pageServer {
+	server := &pageServer{
+		pages: make(map[string]string),
+		cache: make(map[string]*cachedPage),
+		files: http.FileServer(http.Dir(outputDir)),
+	}
+	for _, source := range sources {
+		server.pages["/"+pagePath(source)] = source
+	}
+	return server
+}
+
+func (s *pageServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch p := path.Clean(r.URL.Path); {
+	case p == "/":
+		http.Redirect(w, r, "/"+pagePath(sources[0]), http.StatusFound)
+	case p == "/gocco.css":
+		w.Header().Set("Content-Type", "text/css; charset=utf-8")
+		w.Write([]byte(Css))
+	case p == "/gocco.js":
+		w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+		w.Write([]byte(Js))
+	case s.pages[p] != "":
+		s.servePage(w, s.pages[p])
+	case strings.HasSuffix(p, ".html"):
+		http.NotFound(w, r)
+	default:
+		s.files.ServeHTTP(w, r)
+	}
+}
+
+// render a page, or take it from the cache when its source is unchanged
+func (s *pageServer) servePage(w http.ResponseWriter, source string) {
+	start := time.Now()
+	info, err := os.Stat(source)
+	if err != nil {
+		metrics.request("error", time.Since(start))
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	s.cacheLock.Lock()
+	cached := s.cache[source]
+	s.cacheLock.Unlock()
+	if cached != nil && cached.modTime.Equal(info.ModTime()) {
+		metrics.cacheHit()
+	} else {
+		metrics.cacheMiss()
+		var html []byte
+		rendered := make(chan []byte, 1)
+		err := isolate(source, func() {
+			sections, meta, err := command.loadSections(source)
+			if err != nil {
+				inputError(err)
+				return
+			}
+			_, page, err := command.renderPage(source, sections, meta)
+			if err != nil {
+				renderFailure(source, ": ", err)
+				return
+			}
+			rendered <- page
+		})
+		if err == nil {
+			select {
+			case html = <-rendered:
+			default:
+			}
+		}
+		if err != nil || html == nil {
+			metrics.request("error", time.Since(start))
+			http.Error(w, "gocco: rendering "+source+" failed", http.StatusInternalServerError)
+			return
+		}
+		cached = &cachedPage{html, info.ModTime()}
+		s.cacheLock.Lock()
+		s.cache[source] = cached
+		s.cacheLock.Unlock()
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	html := cached.html
+	if s.reloads != nil {
+		html = bytes.Replace(html, []byte("</body>"), []byte(reloadScript+"</body>"), 1)
+	}
+	w.Write(html)
+	metrics.request("ok", time.Since(start))
+}
+
+// ## Live reload
+
+// where pages listen for their source being saved
+const reloadPath = "/_gocco/reload"
+
+// the script the served pages get, reloading the page when the server
+// names it
+const reloadScript = `<script>
+(function () {
+  var events = new EventSource("` + reloadPath + `");
+  events.onmessage = function (event) {
+    if (location.pathname === "/" + event.data) {
+      location.reload();
+    }
+  };
+})();
+</script>
+`
+
+// `serveReloads` keeps an event stream open to a page, sending it the
+// path of every page whose source was saved
+func (s *pageServer) serveReloads(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher.Flush()
+
+	pages := make(chan string, 16)
+	s.reloadsLock.Lock()
+	s.reloads[pages] = true
+	s.reloadsLock.Unlock()
+	defer func() {
+		s.reloadsLock.Lock()
+		delete(s.reloads, pages)
+		s.reloadsLock.Unlock()
+	}()
+	for {
+		select {
+		case page := <-pages:
+			fmt.Fprintf(w, "data: %s\n\n", page)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// tell the open pages which sources were saved
+func (s *pageServer) reloadPages(changed map[string]bool) {
+	s.reloadsLock.Lock()
+	defer s.reloadsLock.Unlock()
+	for _, source := range sources {
+		if !changed[filepath.Clean(source)] {
+			continue
+		}
+		log.Println("gocco: reloading ", pagePath(source))
+		for pages := range s.reloads {
+			select {
+			case pages <- pagePath(source):
+			default:
+				// a page too slow to take it will reload some other time
+			}
+		}
+	}
+}
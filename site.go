@@ -0,0 +1,114 @@
+package gocco
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ## Publishing
+
+// the address the docs are published at, set with `-base-url`
+var baseURL string
+
+// markup injected into the head of every page, usually an analytics
+// snippet, read from the file given to `-analytics`
+var analytics string
+
+// read the `-analytics` snippet
+func loadAnalytics(file string) {
+	snippet, err := ioutil.ReadFile(file)
+	if err != nil {
+		fail(exitConfig, err)
+	}
+	analytics = expandEnv(file, string(snippet))
+}
+
+// ### Footer
+// With `-footer` pages end with the time they were generated, formatted
+// with `-time-format` in the `-time-zone` location. Reproducible builds
+// can pin the time through `SOURCE_DATE_EPOCH`, or leave the footer out
+
+var footer bool
+var timeFormat = "2006-01-02 15:04 MST"
+var timeZone string
+
+// when the docs were generated, zero without a footer
+var generatedAt time.Time
+
+// work out the generation time once, so every page agrees on it
+func setGeneratedAt() error {
+	if !footer {
+		return nil
+	}
+	generatedAt = time.Now()
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		seconds, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid SOURCE_DATE_EPOCH %q", epoch)
+		}
+		generatedAt = time.Unix(seconds, 0).UTC()
+	}
+	if timeZone != "" {
+		location, err := time.LoadLocation(timeZone)
+		if err != nil {
+			return err
+		}
+		generatedAt = generatedAt.In(location)
+	}
+	return nil
+}
+
+// the footer line, empty without a footer
+func generatedText() string {
+	if generatedAt.IsZero() {
+		return ""
+	}
+	return strings.Replace(message("generated"), "{time}", generatedAt.Format(timeFormat), -1)
+}
+
+// the name of the whole site and the description of pages that don't
+// have their own, set with `-title` and `-description`
+var siteTitle string
+var siteDescription string
+
+// the name of the site where one is needed
+func siteName() string {
+	if siteTitle != "" {
+		return siteTitle
+	}
+	return "Documentation"
+}
+
+// the absolute address of a file in `docs/`, empty when no base URL
+// was given
+func siteURL(file string) string {
+	if baseURL == "" {
+		return ""
+	}
+	return strings.TrimSuffix(baseURL, "/") + "/" + filepath.ToSlash(file)
+}
+
+// write `docs/404.html`, which hosts serve for unknown addresses
+func writeNotFound() {
+	html, err := renderTemplate("404", NotFoundHTML, map[string]interface{}{
+		"BaseURL":   strings.TrimSuffix(baseURL, "/"),
+		"Sources":   listedSources(sources),
+		"Analytics": analytics,
+		"SiteTitle": siteTitle,
+	})
+	dest := filepath.Join(outputDir, "404.html")
+	if err == nil {
+		err = writeFile(dest, html)
+	}
+	if err != nil {
+		renderFailure(err)
+		return
+	}
+	log.Println("gocco: 404 page -> ", dest)
+}
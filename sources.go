@@ -0,0 +1,252 @@
+package gocco
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ## Collecting sources
+
+// a `symlinkPolicy` decides what happens to symbolic links among the
+// inputs: `follow` documents the file they point to, `skip` ignores them
+type symlinkPolicy string
+
+const (
+	followSymlinks symlinkPolicy = "follow"
+	skipSymlinks   symlinkPolicy = "skip"
+)
+
+func (p *symlinkPolicy) String() string {
+	return string(*p)
+}
+
+func (p *symlinkPolicy) Set(value string) error {
+	switch symlinkPolicy(value) {
+	case followSymlinks, skipSymlinks:
+		*p = symlinkPolicy(value)
+		return nil
+	}
+	return fmt.Errorf("unknown symlink policy %q, use follow or skip", value)
+}
+
+// the policy in effect, set with `-symlinks`
+var symlinks = followSymlinks
+
+// the languages to document, by their Pygments name, as given to `-lang`.
+// When empty every known language is documented
+var onlyLanguages = make(map[string]bool)
+
+// parse the comma separated `-lang` list
+func setLanguageFilter(list string) {
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			onlyLanguages[strings.ToLower(name)] = true
+		}
+	}
+}
+
+// whether a source is in one of the languages selected with `-lang`
+func languageSelected(source string) bool {
+	if len(onlyLanguages) == 0 {
+		return true
+	}
+	language := getLanguage(source)
+	return language != nil && onlyLanguages[language.name]
+}
+
+// whether directories among the arguments are searched for sources,
+// set with `-recursive`
+var recursive bool
+
+// `isBinary` guesses whether a file holds something other than text the
+// same way git does, by looking for a NUL among its first bytes
+func isBinary(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, 8000)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return false
+	}
+	return bytes.IndexByte(head[:n], 0) >= 0
+}
+
+// `walkSources` finds the sources below `dir`, in a stable order. Hidden
+// directories like `.git` and the output directory are not entered, and
+// files in unknown languages are passed over quietly since a tree holds
+// plenty besides sources. Symbolic links are treated as `-symlinks`
+// says, and a linked directory is only entered if it wasn't already,
+// which stops links that loop back up the tree
+func walkSources(dir string, seen map[string]bool) []string {
+	out, _ := filepath.Abs(outputDir)
+	var files []string
+	var walk func(dir string)
+	walk = func(dir string) {
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			inputError(err)
+			return
+		}
+		for _, info := range entries {
+			path := filepath.Join(dir, info.Name())
+			link := info.Mode()&os.ModeSymlink != 0
+			if link {
+				if symlinks == skipSymlinks {
+					warn(path, 0, "skipping symlink")
+					continue
+				}
+				// `Stat` follows the link, and fails on dangling ones
+				if info, err = os.Stat(path); err != nil {
+					inputError(err)
+					continue
+				}
+			}
+			real, err := realPath(path)
+			if err != nil {
+				inputError(err)
+				continue
+			}
+			if info.IsDir() {
+				abs, _ := filepath.Abs(path)
+				if strings.HasPrefix(info.Name(), ".") || abs == out || excluded(path) {
+					continue
+				}
+				if seen[real] {
+					if link {
+						warn(path, 0, "links to "+real+", which is already searched, skipping")
+					}
+					continue
+				}
+				seen[real] = true
+				walk(path)
+				continue
+			}
+			if !info.Mode().IsRegular() || getLanguage(path) == nil || !languageSelected(path) || seen[real] {
+				continue
+			}
+			if isBinary(path) {
+				warn(path, 0, "binary, skipping")
+				continue
+			}
+			seen[real] = true
+			files = append(files, path)
+		}
+	}
+	if real, err := realPath(dir); err == nil {
+		seen[real] = true
+	}
+	walk(dir)
+	return files
+}
+
+// the absolute path of a file with every link on the way resolved.
+// `EvalSymlinks` fails on dangling links and cycles
+func realPath(path string) (string, error) {
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(real)
+}
+
+// `collectSources` turns the command line arguments into the list of
+// files to document. Every file is resolved to its real location so that
+// one reachable under several names is only documented once, and links
+// that loop back onto themselves are reported instead of followed
+func collectSources(args []string) []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, arg := range args {
+		if isURL(arg) {
+			name, err := download(arg)
+			if err != nil {
+				inputError(err)
+				continue
+			}
+			if getLanguage(name) == nil {
+				warn(arg, 0, "no language known, skipping")
+				continue
+			}
+			if languageSelected(name) && !seen[name] {
+				seen[name] = true
+				files = append(files, name)
+			}
+			continue
+		}
+		if isArchive(arg) {
+			names, err := openArchive(arg)
+			if err != nil {
+				inputError(err)
+				continue
+			}
+			// archives hold plenty besides sources, so files in unknown
+			// languages are passed over quietly
+			for _, name := range names {
+				if getLanguage(name) == nil || !languageSelected(name) || seen[name] {
+					continue
+				}
+				seen[name] = true
+				files = append(files, name)
+			}
+			continue
+		}
+		info, err := os.Lstat(arg)
+		if err != nil {
+			inputError(err)
+			continue
+		}
+		if info.Mode()&os.ModeSymlink != 0 && symlinks == skipSymlinks {
+			warn(arg, 0, "skipping symlink")
+			continue
+		}
+		// `EvalSymlinks` fails on dangling links and cycles
+		real, err := filepath.EvalSymlinks(arg)
+		if err != nil {
+			inputError(err)
+			continue
+		}
+		real, err = filepath.Abs(real)
+		if err != nil {
+			log.Println("gocco: ", err)
+			continue
+		}
+		if info, err := os.Stat(real); err == nil && info.IsDir() {
+			if !recursive {
+				warn(arg, 0, "a directory, pass -recursive to document the sources in it")
+				continue
+			}
+			files = append(files, walkSources(arg, seen)...)
+			continue
+		}
+		if getLanguage(arg) == nil {
+			warn(arg, 0, "no language known, skipping")
+			continue
+		}
+		if !languageSelected(arg) {
+			continue
+		}
+		if seen[real] {
+			warn(arg, 0, "a duplicate of an earlier source, skipping")
+			continue
+		}
+		seen[real] = true
+		files = append(files, arg)
+	}
+	// drop what the configuration excludes
+	kept := files[:0]
+	for _, file := range files {
+		if !excluded(file) {
+			kept = append(kept, file)
+		}
+	}
+	return kept
+}
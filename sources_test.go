@@ -0,0 +1,122 @@
+package gocco
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// a tree of files, by their slash separated path relative to the tree
+func makeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "gocco")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	for name, contents := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestCollectSources(t *testing.T) {
+	setupOnce.Do(setup)
+	dir := makeTree(t, map[string]string{
+		"a.go":          "package a\n",
+		"Makefile":      "all:\n",
+		".bashrc":       "alias ls='ls -F'\n",
+		".hidden.go":    "package a\n",
+		"lib/b.go":      "package lib\n",
+		"lib/c.py":      "# c\n",
+		"lib/.git/d.go": "package git\n",
+		"lib/README":    "lib\n",
+		"bin/e.go":      "\x00\x01",
+	})
+	defer func(r bool, dir string) { recursive, outputDir = r, dir }(recursive, outputDir)
+	outputDir = filepath.Join(dir, "docs")
+	join := func(names ...string) []string {
+		paths := make([]string, len(names))
+		for i, name := range names {
+			paths[i] = filepath.Join(dir, filepath.FromSlash(name))
+		}
+		return paths
+	}
+	tests := []struct {
+		name      string
+		recursive bool
+		args      []string
+		want      []string
+	}{
+		{"files", false, join("a.go", "lib/c.py"), join("a.go", "lib/c.py")},
+		{"extensionless and dotfiles", false, join("Makefile", ".bashrc", "a.go"), join("a.go")},
+		{"duplicates", false, join("a.go", "lib/../a.go"), join("a.go")},
+		{"directory without -recursive", false, join("lib"), nil},
+		// dotfiles in a known language are sources, hidden directories are
+		// passed over
+		{"directory", true, join("."), join(".hidden.go", "a.go", "lib/b.go", "lib/c.py")},
+	}
+	for _, test := range tests {
+		recursive = test.recursive
+		got := collectSources(test.args)
+		if len(got) == 0 && len(test.want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: collectSources(%q) = %q, want %q", test.name, test.args, got, test.want)
+		}
+	}
+}
+
+func TestCollectSourcesSymlinks(t *testing.T) {
+	setupOnce.Do(setup)
+	dir := makeTree(t, map[string]string{
+		"tree/a.go":  "package a\n",
+		"other/b.go": "package b\n",
+	})
+	links := map[string]string{
+		// a second name for a source, a linked directory and one looping
+		// back up the tree
+		"tree/c.go":     "a.go",
+		"tree/other":    "../other",
+		"tree/sub/up":   "../..",
+		"tree/sub/d.go": "../../other/b.go",
+	}
+	for name, target := range links {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(filepath.FromSlash(target), path); err != nil {
+			t.Skip("no symlinks: ", err)
+		}
+	}
+	defer func(r bool, p symlinkPolicy, dir string) { recursive, symlinks, outputDir = r, p, dir }(recursive, symlinks, outputDir)
+	recursive, outputDir = true, filepath.Join(dir, "docs")
+	tree := filepath.Join(dir, "tree")
+	tests := []struct {
+		policy symlinkPolicy
+		want   []string
+	}{
+		{followSymlinks, []string{"a.go", "other/b.go"}},
+		{skipSymlinks, []string{"a.go"}},
+	}
+	for _, test := range tests {
+		symlinks = test.policy
+		var want []string
+		for _, name := range test.want {
+			want = append(want, filepath.Join(tree, filepath.FromSlash(name)))
+		}
+		if got := collectSources([]string{tree}); !reflect.DeepEqual(got, want) {
+			t.Errorf("-symlinks %s: collectSources(%q) = %q, want %q", test.policy, tree, got, want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package gocco
+
+import (
+	_ "embed"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// ## Spelling
+
+// The dictionary holds base words only, taken from the en_US hunspell
+// dictionary. Inflected forms are recognised by undoing the affix rules
+// of that dictionary until a known word is left, without checking that
+// the rule applies to that word, which lets a few made up words pass but
+// keeps the list small
+
+//go:embed dictionary.txt
+var dictionaryText string
+
+// an `affix` is a suffix rule: `add` replaces `strip` at the end of a
+// word matching `condition`
+type affix struct {
+	add, strip string
+	condition  *regexp.Regexp
+}
+
+func suffix(add, strip, condition string) affix {
+	return affix{add, strip, regexp.MustCompile(condition + "$")}
+}
+
+var suffixes = []affix{
+	suffix("ive", "e", "e"), suffix("ive", "", "[^e]"),
+	suffix("ion", "e", "e"), suffix("ication", "y", "y"), suffix("en", "", "[^ey]"),
+	suffix("ions", "e", "e"), suffix("ications", "y", "y"), suffix("ens", "", "[^ey]"),
+	suffix("ieth", "y", "y"), suffix("th", "", "[^y]"),
+	suffix("ly", "", "."),
+	suffix("ing", "e", "e"), suffix("ing", "", "[^e]"),
+	suffix("ings", "e", "e"), suffix("ings", "", "[^e]"),
+	suffix("d", "", "e"), suffix("ied", "y", "[^aeiou]y"), suffix("ed", "", "[^ey]"), suffix("ed", "", "[aeiou]y"),
+	suffix("st", "", "[eg]"), suffix("iest", "y", "[^aeiou]y"), suffix("est", "", "[aeiou]y"), suffix("est", "", "[^ey]"),
+	suffix("r", "", "e"), suffix("ier", "y", "[^aeiou]y"), suffix("er", "", "[aeiou]y"), suffix("er", "", "[^ey]"),
+	suffix("rs", "", "e"), suffix("iers", "y", "[^aeiou]y"), suffix("ers", "", "[aeiou]y"), suffix("ers", "", "[^ey]"),
+	suffix("ies", "y", "[^aeiou]y"), suffix("s", "", "[aeiou]y"), suffix("es", "", "[sxzh]"), suffix("s", "", "[^sxzhy]"),
+	suffix("iness", "y", "[^aeiou]y"), suffix("ness", "", "[aeiou]y"), suffix("ness", "", "[^y]"),
+	suffix("able", "", "[^aeiou]"), suffix("able", "", "ee"), suffix("able", "e", "[^aeiou]e"),
+	suffix("ment", "", "."),
+}
+
+var prefixes = []string{"re", "in", "un", "de", "dis", "con", "pro"}
+
+// words programmers' prose is full of that an English dictionary lacks
+var programmingWords = strings.Fields(`
+	api app args argv arity ascii async auth backend backtick bool
+	boolean builtin callback changelog charset cli codebase config const
+	css csv dedupe deps dir docco docstring dotfile enum env eof errno
+	filename filesystem func gocco golang goroutine hashmap html http
+	https init inline io iterator json lexer lookup markdown metadata
+	middleware mutex namespace nil pygments regex regexp repo runtime
+	stderr stdin stdlib stdout struct subcommand substring symlink
+	syscall toc tokenizer toolchain tuple typedef umask unicode
+	unmarshal url utf validator whitespace yaml
+`)
+
+var dictionary map[string]bool
+var dictionaryOnce sync.Once
+
+// load the embedded dictionary, the programming words and `extra`,
+// usually the project's own word list
+func loadDictionary(extra []string) {
+	dictionaryOnce.Do(func() {
+		dictionary = make(map[string]bool)
+		for _, word := range strings.Split(dictionaryText, "\n") {
+			if word != "" && !strings.HasPrefix(word, "#") {
+				dictionary[word] = true
+			}
+		}
+		for _, word := range programmingWords {
+			dictionary[word] = true
+		}
+		for _, word := range extra {
+			dictionary[strings.ToLower(word)] = true
+		}
+	})
+}
+
+// `spelledRight` reports whether a lowercase word is in the dictionary,
+// directly or once affixes are taken off
+func spelledRight(word string) bool {
+	return knownWord(word, 0)
+}
+
+func knownWord(word string, depth int) bool {
+	if dictionary[word] {
+		return true
+	}
+	// every rule shortens the word, so this is only a safety net
+	if depth > 4 {
+		return false
+	}
+	for _, rule := range suffixes {
+		if !strings.HasSuffix(word, rule.add) || len(word) == len(rule.add) {
+			continue
+		}
+		base := word[:len(word)-len(rule.add)] + rule.strip
+		if rule.condition.MatchString(base) && knownWord(base, depth+1) {
+			return true
+		}
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(word, prefix) && len(word) > len(prefix)+1 &&
+			knownWord(word[len(prefix):], depth+1) {
+			return true
+		}
+	}
+	return false
+}
+
+// ### Finding words
+
+// inline code, links and anything that looks like an identifier is not
+// prose, and is blanked out before looking for words
+var notProse = regexp.MustCompile("`[^`]*`" + `|\]\([^)]*\)|<[^>]*>|\w+://\S+|\w+(?:[._/]\w+)+|\w*\d\w*`)
+
+var wordPattern = regexp.MustCompile(`[\pL]+(?:['’]\pL+)?`)
+
+// the stems of negative contractions that aren't words themselves
+var contractions = map[string]bool{"ca": true, "wo": true, "sha": true}
+
+// `misspellings` lists the misspelled words of a line of prose. Words
+// with capitals are taken to be names or identifiers and left alone,
+// and only words in the Latin script are checked
+func misspellings(text string) []string {
+	var wrong []string
+	text = notProse.ReplaceAllString(text, " ")
+	for _, word := range wordPattern.FindAllString(text, -1) {
+		if strings.ToLower(word) != word || !latin(word) {
+			continue
+		}
+		// possessives and contractions are checked by their stem
+		stem := strings.Replace(word, "’", "'", -1)
+		if strings.HasSuffix(stem, "n't") {
+			stem = strings.TrimSuffix(stem, "n't")
+			if contractions[stem] {
+				continue
+			}
+		} else if i := strings.Index(stem, "'"); i > 0 {
+			stem = stem[:i]
+		}
+		if !spelledRight(stem) {
+			wrong = append(wrong, word)
+		}
+	}
+	return wrong
+}
+
+func latin(word string) bool {
+	for _, r := range word {
+		if !unicode.Is(unicode.Latin, r) && r != '\'' && r != '’' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,186 @@
+package gocco
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ## Statistics
+
+// `-stats` writes `docs/stats.html`, a table of every source with its
+// lines of code and of docs, its sections and when it last changed. The
+// columns sort with a click on their heading. Given the profile of
+// `go test -coverprofile`, with `-coverprofile`, there is a column for
+// the share of statements the tests cover too
+var stats bool
+
+// the coverage profile read for `-stats`
+var coverProfile string
+
+// a `FileStats` is a row of the table
+type FileStats struct {
+	Source   string
+	Href     string
+	Docs     int
+	Code     int
+	Sections int
+	Modified time.Time
+	// The statements in the coverage profile, and how many of them
+	// ran, both zero when the profile has none of the file
+	Statements int
+	Covered    int
+}
+
+// the share of comment lines, in percent
+func (s *FileStats) Density() int {
+	if s.Docs+s.Code == 0 {
+		return 0
+	}
+	return 100 * s.Docs / (s.Docs + s.Code)
+}
+
+// the share of statements covered, in tenths of a percent so the column
+// sorts finely, -1 without statements
+func (s *FileStats) Coverage() int {
+	if s.Statements == 0 {
+		return -1
+	}
+	return 1000 * s.Covered / s.Statements
+}
+
+// the coverage as it's shown
+func (s *FileStats) CoverageText() string {
+	if s.Statements == 0 {
+		return "–"
+	}
+	return fmt.Sprintf("%.1f%%", float64(s.Covered)*100/float64(s.Statements))
+}
+
+// `readCoverProfile` sums up the statements of a coverage profile by
+// file. Its lines look like
+//
+//     github.com/nikhilm/gocco/gocco.go:36.42,38.2 1 5
+//
+// with the statements of a block and how often it ran
+func readCoverProfile(file string) (map[string][2]int, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	counts := make(map[string][2]int)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if n == 1 && strings.HasPrefix(line, "mode:") || line == "" {
+			continue
+		}
+		colon := strings.LastIndex(line, ":")
+		fields := strings.Fields(line[colon+1:])
+		if colon < 0 || len(fields) != 3 {
+			return nil, fmt.Errorf("%s:%d: not a coverage profile", file, n)
+		}
+		statements, err1 := strconv.Atoi(fields[1])
+		count, err2 := strconv.Atoi(fields[2])
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("%s:%d: not a coverage profile", file, n)
+		}
+		c := counts[line[:colon]]
+		c[0] += statements
+		if count > 0 {
+			c[1] += statements
+		}
+		counts[line[:colon]] = c
+	}
+	return counts, scanner.Err()
+}
+
+// the counts of a source in the profile, which names files by their
+// import path, so the source is matched by the end of it
+func sourceCoverage(counts map[string][2]int, source string) [2]int {
+	path := filepath.ToSlash(filepath.Clean(source))
+	for name, c := range counts {
+		if name == path || strings.HasSuffix(name, "/"+path) {
+			return c
+		}
+	}
+	return [2]int{}
+}
+
+// the rows of the table, in the order of the sources
+func fileStats() []*FileStats {
+	var coverage map[string][2]int
+	if coverProfile != "" {
+		var err error
+		if coverage, err = readCoverProfile(coverProfile); err != nil {
+			warn(coverProfile, 0, "no coverage column: "+err.Error())
+		}
+	}
+	densitiesLock.Lock()
+	defer densitiesLock.Unlock()
+	var rows []*FileStats
+	for _, source := range listedSources(sources) {
+		counts, ok := densities[source]
+		if !ok {
+			continue
+		}
+		row := &FileStats{Source: filepath.ToSlash(source), Href: pagePath(source),
+			Docs: counts[0], Code: counts[1], Sections: counts[2]}
+		if info, err := os.Stat(source); err == nil {
+			row.Modified = info.ModTime()
+		}
+		if coverage != nil {
+			c := sourceCoverage(coverage, source)
+			row.Statements, row.Covered = c[0], c[1]
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func writeStats() {
+	rows := fileStats()
+	total := &FileStats{Source: "total"}
+	for _, row := range rows {
+		total.Docs += row.Docs
+		total.Code += row.Code
+		total.Sections += row.Sections
+		total.Statements += row.Statements
+		total.Covered += row.Covered
+		if row.Modified.After(total.Modified) {
+			total.Modified = row.Modified
+		}
+	}
+	// the script is written first, the page links to it by its name
+	var err error
+	if csp {
+		err = writeAsset("gocco-stats.js", []byte(StatsJs))
+	}
+	var html []byte
+	if err == nil {
+		html, err = renderTemplate("stats", StatsHTML, map[string]interface{}{
+			"Title":     siteName(),
+			"Files":     rows,
+			"Total":     total,
+			"Coverage":  coverProfile != "",
+			"Index":     indexPage(),
+			"Analytics": analytics,
+			"CSP":       csp,
+		})
+	}
+	dest := filepath.Join(outputDir, "stats.html")
+	if err == nil {
+		err = writeFile(dest, html)
+	}
+	if err != nil {
+		renderFailure(err)
+		return
+	}
+	log.Println("gocco: stats -> ", dest)
+}
@@ -0,0 +1,190 @@
+package gocco
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// ## Styles
+
+// `gocco.css` carries a default colour scheme. Any other Pygments style
+// is turned into a stylesheet scoped to the code column, which takes
+// precedence over the default
+
+// stylesheets already produced, by style name, since many pages usually
+// share one
+var styleSheets = make(map[string]string)
+var styleSheetsLock sync.Mutex
+
+// `styleCSS` returns the stylesheet for a Pygments style. With `-csp` the
+// pages can't carry it inline, so it is also written next to them as
+// `gocco-<style>.css`
+func styleCSS(style string) (string, error) {
+	styleSheetsLock.Lock()
+	defer styleSheetsLock.Unlock()
+	if css, ok := styleSheets[style]; ok {
+		return css, nil
+	}
+	css, err := pygmentsCSS(style, "td.code")
+	if err != nil {
+		return "", err
+	}
+	styleSheets[style] = css
+	if csp {
+		if err := writeAsset("gocco-"+style+".css", []byte(css)); err != nil {
+			return "", err
+		}
+	}
+	return css, nil
+}
+
+// ask Pygments for the rules of a style, prefixed with `scope`. Only the
+// rules under the scope are kept, Pygments also emits a few global ones
+// that would restyle the rest of the page
+func pygmentsCSS(style, scope string) (string, error) {
+	output, err := exec.Command("pygmentize", "-S", style, "-f", "html", "-a", scope).Output()
+	if err != nil {
+		return "", err
+	}
+	css := new(bytes.Buffer)
+	for _, line := range bytes.Split(output, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte(scope)) {
+			css.Write(line)
+			css.WriteString("\n")
+		}
+	}
+	if css.Len() == 0 {
+		return "", errors.New("unknown style " + style)
+	}
+	return css.String(), nil
+}
+
+// the name of the stylesheet for a style
+func styleFile(style string) string {
+	return assetName("gocco-" + style + ".css")
+}
+
+// the style of every page without one in its front matter, from
+// `-style` or the configuration. Empty keeps the colours of `gocco.css`
+var codeStyle string
+
+// make sure Pygments knows the style asked for, before any page needs it
+func checkStyle(style string) error {
+	// highlight.js has themes of its own, which only the browser sees
+	if style == "" || clientHighlighting() {
+		return nil
+	}
+	if _, err := pygmentsCSS(style, "td.code"); err != nil {
+		return fmt.Errorf("unknown style %s, -list-styles shows the known ones", style)
+	}
+	return nil
+}
+
+// print the names of the styles, for `-list-styles`
+func listStyles() {
+	styles, err := pygmentsStyles()
+	if err != nil {
+		fail(exitRender, "listing styles: ", err)
+	}
+	for _, style := range styles {
+		fmt.Println(style)
+	}
+}
+
+// the names of every style Pygments knows, from the `* name:` lines
+// of its listing
+func pygmentsStyles() ([]string, error) {
+	output, err := exec.Command("pygmentize", "-L", "styles").Output()
+	if err != nil {
+		return nil, err
+	}
+	var styles []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.HasPrefix(line, "* ") {
+			styles = append(styles, strings.TrimSuffix(strings.TrimPrefix(line, "* "), ":"))
+		}
+	}
+	return styles, nil
+}
+
+// ### The gallery
+
+// a `GalleryStyle` is one entry of the style gallery
+type GalleryStyle struct {
+	Name       string
+	StyleSheet string
+}
+
+// `stylesCommand` implements `gocco styles`, which lists the available
+// styles. With `-preview` it also renders a sample file, or the one
+// given, once per style into `docs/styles.html` so a style can be
+// picked by eye
+func stylesCommand(args []string) {
+	flags := flag.NewFlagSet("styles", flag.ExitOnError)
+	preview := flags.Bool("preview", false, "render a gallery of every style to docs/styles.html")
+	flags.Parse(args)
+
+	if !*preview {
+		listStyles()
+		return
+	}
+	styles, err := pygmentsStyles()
+	if err != nil {
+		fail(exitRender, "listing styles: ", err)
+	}
+
+	sample, name := []byte(SampleSource), "sample.go"
+	if flags.NArg() > 0 {
+		name = flags.Arg(0)
+		if sample, err = ioutil.ReadFile(name); err != nil {
+			fail(exitInput, err)
+		}
+	}
+	language := getLanguage(name)
+	if language == nil {
+		fail(exitInput, "no language known for ", name)
+	}
+	// the markup Pygments produces doesn't depend on the style, so the
+	// sample is highlighted once and only the stylesheets differ
+	pygments := exec.Command("pygmentize", "-l", language.name, "-f", "html", "-O", "encoding=utf-8")
+	pygments.Stdin = bytes.NewReader(sample)
+	code, err := pygments.Output()
+	if err != nil {
+		fail(exitRender, "highlighting ", name, ": ", err)
+	}
+
+	var gallery []*GalleryStyle
+	for _, style := range styles {
+		css, err := pygmentsCSS(style, "#style-"+style)
+		if err != nil {
+			log.Println("gocco: ", err)
+			continue
+		}
+		gallery = append(gallery, &GalleryStyle{style, css})
+	}
+
+	t := template.Must(template.New("styles").Parse(StylesHTML))
+	buf := new(bytes.Buffer)
+	err = t.Execute(buf, map[string]interface{}{
+		"Source": filepath.Base(name),
+		"Code":   string(code),
+		"Styles": gallery,
+	})
+	if err != nil {
+		fail(exitRender, err)
+	}
+	dest := filepath.Join(outputDir, "styles.html")
+	if err := writeFile(dest, buf.Bytes()); err != nil {
+		fail(exitRender, err)
+	}
+	log.Println("gocco: ", len(gallery), " styles -> ", dest)
+}
@@ -0,0 +1,79 @@
+package gocco
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"text/template"
+)
+
+// ## Custom templates
+
+// Organizations branding their docs can replace the page template with
+// `-template page.html`, and the stylesheet with `-css site.css`. Both
+// can go into `gocco.json` too, as `"templates": {"gocco": ...}` and
+// `"css"`, the flags win.
+//
+// The template is Go's `text/template` and gets a `TemplateData`, whose
+// fields are described where it's declared. The ones most pages use:
+//
+//   - `.Title`, `.SiteTitle`, `.Description` and `.Path`, the source
+//   - `.Root`, the way back to the output directory, to prefix the
+//     address of `gocco.css` and other shared files with
+//   - `.Sections`, each with `.DocsHTML`, `.CodeHTML`, its anchors
+//     `.ID` and `.Anchor`, its number `.Index`, and `.StartLine` and
+//     `.EndLine`
+//   - `.Jump` and `.Multiple`, the links of the jump menu
+//   - `.Prev` and `.Next`, the neighbouring pages
+//
+// Besides the functions of `text/template` it can call `asset` for the
+// name of a shared file, `t` for a message in the language of the
+// labels, `js` for the page script, `page` for the page of a source and
+// `env` for an allowed environment variable.
+//
+// The template is tried on a made-up page when gocco starts, so a
+// misspelled field stops the run before any page is written
+
+// `setPageTemplate` replaces the page template with the one in `file`
+func setPageTemplate(file string) error {
+	text, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	templateOverrides["gocco"] = string(text)
+	return nil
+}
+
+// `setStyleSheet` replaces `gocco.css` with the stylesheet in `file`
+func setStyleSheet(file string) error {
+	css, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	Css = string(css)
+	return nil
+}
+
+// `checkPageTemplate` renders a page of one section with the page
+// template, to catch fields and functions it doesn't have
+func checkPageTemplate() error {
+	text, ok := templateOverrides["gocco"]
+	if !ok {
+		return nil
+	}
+	t, err := template.New("gocco").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return err
+	}
+	sample := TemplateData{
+		Title:    "sample.go",
+		Path:     "sample.go",
+		Sections: []*TemplateSection{{DocsHTML: "<p>docs</p>", CodeHTML: "code", Index: 1, ID: "section-1", StartLine: 1, EndLine: 1}},
+		Sources:  []string{"sample.go"},
+		Jump:     []*JumpLink{{Name: "sample.go", Href: "sample.html"}},
+	}
+	if err := t.Execute(new(bytes.Buffer), sample); err != nil {
+		return fmt.Errorf("the page template doesn't fit the pages: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package gocco
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ## Verifying themes
+
+// A change to the templates, the stylesheet or a Pygments style shows
+// on every page, and is easy to miss in review. `gocco verify-theme dir`
+// renders a small corpus of bundled sources and compares the pages to
+// the golden copies recorded in `dir`:
+//
+//     gocco verify-theme -update testdata/default
+//     gocco verify-theme testdata/default
+//
+// With `-style` the pages use that Pygments style, whose stylesheet is
+// recorded as `style.css` next to them
+var themeCorpus = map[string]string{
+	"sample.go": SampleSource,
+	"prose.go":  SampleProse,
+}
+
+// `renderCorpus` renders every page of the corpus, and the stylesheet,
+// by the name of their golden file
+func renderCorpus(style string) (map[string][]byte, error) {
+	meta := FrontMatter{}
+	pages := make(map[string][]byte)
+	if style != "" {
+		css, err := styleCSS(style)
+		if err != nil {
+			return nil, err
+		}
+		meta["style"] = style
+		pages["style.css"] = []byte(css)
+	}
+	sources = nil
+	for name := range themeCorpus {
+		sources = append(sources, name)
+	}
+	sort.Strings(sources)
+	commandSettings()
+	for _, name := range sources {
+		sections, _ := command.prepareSections(name, []byte(themeCorpus[name]))
+		dest, html, err := command.renderPage(name, sections, meta)
+		if err != nil {
+			return nil, err
+		}
+		pages[filepath.Base(dest)] = html
+	}
+	return pages, nil
+}
+
+func verifyThemeCommand(args []string) {
+	flags := flag.NewFlagSet("verify-theme", flag.ExitOnError)
+	style := flags.String("style", "", "Pygments style to render the corpus with")
+	update := flags.Bool("update", false, "record the current output as the golden files")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fail(exitConfig, "verify-theme: give the directory of golden files")
+	}
+	dir := flags.Arg(0)
+
+	pages, err := renderCorpus(*style)
+	if err != nil {
+		fail(exitRender, err)
+	}
+	var names []string
+	for name := range pages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if *update {
+		if err := ensureDirectory(dir); err != nil {
+			fail(exitRender, err)
+		}
+		for _, name := range names {
+			if err := writeFile(filepath.Join(dir, name), pages[name]); err != nil {
+				fail(exitRender, err)
+			}
+		}
+		fmt.Println("recorded", len(names), "golden files in", dir)
+		return
+	}
+	failed := 0
+	for _, name := range names {
+		golden, err := ioutil.ReadFile(filepath.Join(dir, name))
+		switch {
+		case os.IsNotExist(err):
+			fmt.Printf("%s: no golden file, record one with -update\n", name)
+			failed++
+		case err != nil:
+			fail(exitInput, err)
+		case !bytes.Equal(golden, pages[name]):
+			line, want, got := firstDifference(golden, pages[name])
+			fmt.Printf("%s:%d: differs\n  golden: %s\n  now:    %s\n", name, line, want, got)
+			failed++
+		default:
+			fmt.Printf("%s: ok\n", name)
+		}
+	}
+	if failed > 0 {
+		os.Exit(exitProblems)
+	}
+}
+
+// the first line, counted from 1, on which `a` and `b` differ, and the
+// line in each
+func firstDifference(a, b []byte) (int, string, string) {
+	linesA, linesB := bytes.Split(a, []byte("\n")), bytes.Split(b, []byte("\n"))
+	for i := 0; ; i++ {
+		var lineA, lineB []byte
+		if i < len(linesA) {
+			lineA = linesA[i]
+		}
+		if i < len(linesB) {
+			lineB = linesB[i]
+		}
+		if !bytes.Equal(lineA, lineB) || i >= len(linesA) || i >= len(linesB) {
+			return i + 1, string(bytes.TrimSpace(lineA)), string(bytes.TrimSpace(lineB))
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package gocco
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ## The README's table of contents
+
+// A repository's README is where readers start, so it can list the
+// pages of the docs. `gocco toc [dir]` prints that list for the docs in
+// `dir`, `docs/` by default, as markdown; with `-write README.md` it goes
+// into the README between the markers
+//
+//     <!-- gocco:toc -->
+//     <!-- /gocco:toc -->
+//
+// replacing what the last run put there, or at the end when the README
+// has no markers yet. The pages are found in the manifest of the last
+// build, so run it after gocco
+const (
+	tocStart = "<!-- gocco:toc -->"
+	tocEnd   = "<!-- /gocco:toc -->"
+)
+
+func tocCommand(args []string) {
+	flags := flag.NewFlagSet("toc", flag.ExitOnError)
+	write := flags.String("write", "", "markdown `file` to put the list into, between gocco:toc markers")
+	flags.Parse(args)
+	dir := outputDir
+	if flags.NArg() > 0 {
+		dir = flags.Arg(0)
+	}
+
+	entries, err := readManifest(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		fail(exitInput, "toc: no docs to list, run gocco first: ", err)
+	}
+	from := "."
+	if *write != "" {
+		from = filepath.Dir(*write)
+	}
+	list := tocList(entries, dir, from)
+	if *write == "" {
+		fmt.Print(list)
+		return
+	}
+	if err := writeTOC(*write, list); err != nil {
+		fail(exitRender, "toc: ", err)
+	}
+	log.Println("gocco: toc -> ", *write)
+}
+
+// the pages of the docs in `dir` as a markdown list, linked relative to
+// the directory `from`
+func tocList(entries map[string]*ManifestEntry, dir, from string) string {
+	var pages []*ManifestEntry
+	for _, entry := range entries {
+		// pages are the HTML files made from a source, the rest are
+		// shared files or copies
+		if entry.Source != "" && strings.HasSuffix(entry.Path, ".html") {
+			pages = append(pages, entry)
+		}
+	}
+	sort.Slice(pages, func(i, j int) bool { return pages[i].Source < pages[j].Source })
+	buf := new(strings.Builder)
+	for _, page := range pages {
+		link, err := filepath.Rel(from, filepath.Join(dir, filepath.FromSlash(page.Path)))
+		if err != nil {
+			link = filepath.Join(dir, page.Path)
+		}
+		fmt.Fprintf(buf, "- [%s](%s)\n", page.Source, filepath.ToSlash(link))
+	}
+	return buf.String()
+}
+
+// put `list` between the markers in `file`, adding them at its end when
+// it has none
+func writeTOC(file, list string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	block := tocStart + "\n" + list + tocEnd
+	start := bytes.Index(data, []byte(tocStart))
+	end := bytes.Index(data, []byte(tocEnd))
+	switch {
+	case start >= 0 && end > start:
+		data = append(data[:start:start], append([]byte(block), data[end+len(tocEnd):]...)...)
+	case start >= 0 || end >= 0:
+		return fmt.Errorf("%s: the gocco:toc markers are incomplete", file)
+	default:
+		if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+			data = append(data, '\n')
+		}
+		data = append(data, "\n"+block+"\n"...)
+	}
+	return ioutil.WriteFile(file, data, info.Mode().Perm())
+}
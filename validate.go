@@ -0,0 +1,92 @@
+package gocco
+
+import (
+	"container/list"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ## Validating markup
+
+// Comments may hold raw HTML, and a forgotten `</div>` in one breaks the
+// layout of the whole page in ways that are hard to trace back. With
+// `-validate` the markup of every section's docs is checked for tags
+// that aren't closed or are closed out of order, and the page for ids
+// used more than once. Problems are reported at the comment they come
+// from
+var validate bool
+
+var (
+	htmlTagPattern   = regexp.MustCompile(`(?s)<!--.*?-->|<(/?)([a-zA-Z][a-zA-Z0-9-]*)\b[^>]*?(/?)>`)
+	idPattern        = regexp.MustCompile(`\bid="([^"]+)"`)
+	rawTextPattern   = regexp.MustCompile(`(?is)<(script|style)\b.*?</(script|style)>`)
+	voidElements     = map[string]bool{"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true, "input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true}
+	optionalClosings = map[string]bool{"p": true, "li": true, "dt": true, "dd": true, "tr": true, "td": true, "th": true, "option": true}
+)
+
+// `checkMarkup` lists the problems with the nesting of the tags in a
+// fragment of HTML
+func checkMarkup(fragment string) []string {
+	var problems []string
+	var open []string
+	fragment = rawTextPattern.ReplaceAllString(fragment, "")
+	for _, m := range htmlTagPattern.FindAllStringSubmatch(fragment, -1) {
+		name := strings.ToLower(m[2])
+		if name == "" || voidElements[name] || m[3] == "/" {
+			continue
+		}
+		if m[1] == "" {
+			open = append(open, name)
+			continue
+		}
+		// find the element being closed. The ones opened inside it are
+		// closed along with it, which is only fine for those whose end
+		// tag may be left out
+		i := len(open) - 1
+		for i >= 0 && open[i] != name {
+			i--
+		}
+		if i < 0 {
+			problems = append(problems, "</"+name+"> doesn't close any open element")
+			continue
+		}
+		for _, inner := range open[i+1:] {
+			if !optionalClosings[inner] {
+				problems = append(problems, "<"+inner+"> is never closed")
+			}
+		}
+		open = open[:i]
+	}
+	for _, name := range open {
+		if !optionalClosings[name] {
+			problems = append(problems, "<"+name+"> is never closed")
+		}
+	}
+	return problems
+}
+
+// `validateSections` reports problems with the markup of the docs, and
+// ids the final page uses more than once
+func validateSections(sections *list.List, page []byte) {
+	for e := sections.Front(); e != nil; e = e.Next() {
+		section := e.Value.(*Section)
+		for _, problem := range checkMarkup(string(section.DocsHTML)) {
+			warn(section.source, section.line, problem)
+		}
+	}
+	seen := make(map[string]int)
+	for _, m := range idPattern.FindAllSubmatch(page, -1) {
+		seen[string(m[1])]++
+	}
+	for e := sections.Front(); e != nil; e = e.Next() {
+		section := e.Value.(*Section)
+		for _, m := range idPattern.FindAllSubmatch(section.DocsHTML, -1) {
+			if n := seen[string(m[1])]; n > 1 {
+				warn(section.source, section.line, fmt.Sprintf("id %q is used %d times on the page", m[1], n))
+				// report each id once
+				seen[string(m[1])] = 0
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package gocco
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckMarkup(t *testing.T) {
+	tests := []struct {
+		name     string
+		fragment string
+		want     []string
+	}{
+		{"balanced", `<div class="note"><p>a <em>b</em></p></div>`, nil},
+		{"void and self-closing", `<p>a<br>b<img src="x.png"><span/></p>`, nil},
+		{"optional closings", `<ul><li>a<li>b</ul><table><tr><td>c</table>`, nil},
+		{"never closed", `<div><p>a</p>`, []string{"<div> is never closed"}},
+		{"closed out of order", `<div><em>a</div></em>`, []string{"<em> is never closed", "</em> doesn't close any open element"}},
+		{"stray end tag", `a</span>`, []string{"</span> doesn't close any open element"}},
+		{"case", `<DIV>a</div>`, nil},
+		{"comments", `<!-- <div> -->a`, nil},
+		{"scripts", `<script>if (a <b) {}</script><style>p > a {}</style>`, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := checkMarkup(test.fragment); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("checkMarkup(%q) = %q, want %q", test.fragment, got, test.want)
+			}
+		})
+	}
+}
+
+func TestValidateSections(t *testing.T) {
+	out := new(bytes.Buffer)
+	defer func(w io.Writer, flags int) {
+		log.SetOutput(w)
+		log.SetFlags(flags)
+	}(log.Writer(), log.Flags())
+	log.SetOutput(out)
+	log.SetFlags(0)
+	sections := list.New()
+	sections.PushBack(&Section{DocsHTML: []byte(`<div id="a">`), source: "a.go", line: 3})
+	sections.PushBack(&Section{DocsHTML: []byte(`<p id="a"></p><p id="b"></p>`), source: "a.go", line: 9})
+	page := []byte(`<div id="a"></div><p id="a"></p><p id="b"></p><p id="a"></p>`)
+	validateSections(sections, page)
+	want := []string{
+		"gocco: a.go:3: <div> is never closed",
+		`gocco: a.go:3: id "a" is used 3 times on the page`,
+	}
+	if got := strings.Split(strings.TrimSpace(out.String()), "\n"); !reflect.DeepEqual(got, want) {
+		t.Errorf("reported\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,115 @@
+package gocco
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ## Watching
+
+// With `-watch` gocco builds the docs as usual and then keeps running,
+// rendering the page of a source again every time it is saved, so the
+// page can be kept open next to the editor while writing. Only the page
+// of the changed source is rendered, the index, search and other pages
+// made from every source wait for the next full build.
+//
+// The directories of the sources are watched rather than the sources
+// themselves, since many editors save by writing a new file and
+// renaming it over the old one
+var watch bool
+
+// how long to wait for more events after a save, editors often write a
+// file in several steps
+const watchSettle = 100 * time.Millisecond
+
+// `watchSources` renders the pages of the sources again as they change,
+// until gocco is stopped
+func watchSources() {
+	err := watchFiles(sources, func(changed map[string]bool) {
+		regenerate(changed)
+	})
+	if err != nil {
+		fail(exitConfig, "watch: ", err)
+	}
+}
+
+// `watchFiles` calls `changes` with the files that were saved, by their
+// cleaned path, each time saving settles down. It returns only when
+// watching can't start
+func watchFiles(files []string, changes func(changed map[string]bool)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	watched := make(map[string]bool)
+	for _, file := range files {
+		watched[filepath.Clean(file)] = true
+	}
+	dirs := make(map[string]bool)
+	for file := range watched {
+		dir := filepath.Dir(file)
+		if dirs[dir] {
+			continue
+		}
+		dirs[dir] = true
+		if err := watcher.Add(dir); err != nil {
+			return fmt.Errorf("%s: %v", dir, err)
+		}
+	}
+	log.Println("gocco: watching ", len(watched), " sources")
+
+	changed := make(map[string]bool)
+	settle := time.NewTimer(watchSettle)
+	settle.Stop()
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			source := filepath.Clean(event.Name)
+			if !watched[source] || !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+				continue
+			}
+			changed[source] = true
+			settle.Reset(watchSettle)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Println("gocco: watch: ", err)
+		case <-settle.C:
+			changes(changed)
+			changed = make(map[string]bool)
+		}
+	}
+}
+
+// render the pages of the `changed` sources again
+func regenerate(changed map[string]bool) {
+	if joinName != "" {
+		generateJoined()
+	} else {
+		for _, source := range sources {
+			if changed[filepath.Clean(source)] {
+				command.generateDocumentation(source)
+			}
+		}
+	}
+	// a broken link keeps the pages from being written, but watching
+	// goes on for the fix
+	if holdingPages() {
+		if broken := releasePages(laterPages(nil)); broken > 0 {
+			log.Println("gocco: ", broken, " broken links, pages not written")
+		}
+	}
+	writePDFs()
+	writeAnchors()
+	writeManifest()
+}
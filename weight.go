@@ -0,0 +1,110 @@
+package gocco
+
+import (
+	"container/list"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ## Weights
+
+// Alphabetical order puts `api.go` before `intro.go`. A file can ask for
+// a place with a weight, in its front matter or anywhere in its docs:
+//
+//     // ---
+//     // weight: 10
+//     // ---
+//
+//     // gocco:weight 10
+//
+// Files with a weight come first, lighter ones before heavier ones, and
+// the rest follow in their usual order. The index pages, the jump menu
+// and the previous and next links follow the weights, and in a book
+// they order the chapters within each part
+var weights = make(map[string]int)
+
+// a `gocco:weight` directive, which is taken out of the docs
+var weightPattern = regexp.MustCompile(`(?m)^[ \t]*gocco:weight[ \t]+(\S+)[ \t]*\n?`)
+
+// parse a source quietly, for what has to be known about it before any
+// page is rendered. Nil when it can't be read, the page reports that
+func peek(source string) *list.List {
+	code, err := readSource(source)
+	if err != nil || getLanguage(source) == nil {
+		return nil
+	}
+	return parse(source, code)
+}
+
+// the front matter of parsed sections, left in place
+func peekFrontMatter(sections *list.List) FrontMatter {
+	meta := make(FrontMatter)
+	if sections == nil || sections.Len() == 0 {
+		return meta
+	}
+	match := frontMatterPattern.FindSubmatch(sections.Front().Value.(*Section).docsText)
+	if match == nil {
+		return meta
+	}
+	for _, line := range strings.Split(string(match[1]), "\n") {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) == 2 {
+			meta[strings.ToLower(strings.TrimSpace(parts[0]))] = strings.TrimSpace(parts[1])
+		}
+	}
+	return meta
+}
+
+// remember the weight of `source`, if it gives one
+func findWeight(source string, sections *list.List, meta FrontMatter) {
+	value := meta["weight"]
+	for e := sections.Front(); e != nil && value == ""; e = e.Next() {
+		if match := weightPattern.FindSubmatch(e.Value.(*Section).docsText); match != nil {
+			value = string(match[1])
+		}
+	}
+	if value == "" {
+		return
+	}
+	weight, err := strconv.Atoi(value)
+	if err != nil {
+		warn(source, 0, "weight "+value+" isn't a whole number, ignoring it")
+		return
+	}
+	weights[source] = weight
+}
+
+// put the sources in the order of their weights, keeping the files of a
+// package together with `-by-package` and the chapters of a book in
+// their parts
+func orderSources() {
+	if book != nil {
+		orderChapters()
+		return
+	}
+	sortByWeight(sources)
+	if byPackage {
+		sort.SliceStable(sources, func(i, j int) bool {
+			return filepath.Dir(sources[i]) < filepath.Dir(sources[j])
+		})
+	}
+}
+
+// sort `list` by weight, keeping the order of equal weights and of the
+// files without one
+func sortByWeight(list []string) {
+	if len(weights) == 0 {
+		return
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		wi, oki := weights[list[i]]
+		wj, okj := weights[list[j]]
+		if oki != okj {
+			return oki
+		}
+		return oki && wi < wj
+	})
+}
@@ -0,0 +1,70 @@
+package gocco
+
+import (
+	"bytes"
+	"container/list"
+	"strings"
+)
+
+// ## Whitespace
+
+// Pages show the code as it is, trailing spaces and all, and indentation
+// mixing tabs and spaces that lined up in its author's editor comes out
+// ragged once highlighted. With `-whitespace` such lines are reported
+// while parsing and tidied on the page: trailing whitespace is dropped
+// and mixed indentation is turned into spaces, a tab counting as
+// `-tab-width` of them
+var (
+	tidyWhitespace bool
+	tabWidth       = 4
+)
+
+// `tidyCode` reports and tidies the whitespace of the code of every
+// section, before it is highlighted
+func tidyCode(source string, sections *list.List) {
+	for e := sections.Front(); e != nil; e = e.Next() {
+		section := e.Value.(*Section)
+		// the code of a section follows its docs
+		number := section.line + bytes.Count(section.docsText, []byte("\n"))
+		lines := bytes.Split(section.codeText, []byte("\n"))
+		for i, line := range lines {
+			trimmed := bytes.TrimRight(line, " \t")
+			if len(trimmed) < len(line) {
+				warn(source, number+i, "trailing whitespace")
+			}
+			body := bytes.TrimLeft(trimmed, " \t")
+			indent := trimmed[:len(trimmed)-len(body)]
+			if bytes.IndexByte(indent, ' ') >= 0 && bytes.IndexByte(indent, '\t') >= 0 {
+				warn(source, number+i, "indentation mixes tabs and spaces")
+				trimmed = append([]byte(expandTabs(string(indent))), body...)
+			}
+			lines[i] = trimmed
+		}
+		section.codeText = bytes.Join(lines, []byte("\n"))
+	}
+}
+
+// turn the tabs of an indentation into spaces up to the next tab stop
+func expandTabs(indent string) string {
+	return expandAllTabs(indent, tabWidth)
+}
+
+// the tabs of every line of `text` as spaces, `width` columns apart
+func expandAllTabs(text string, width int) string {
+	buf := new(strings.Builder)
+	column := 0
+	for _, r := range text {
+		switch r {
+		case '\t':
+			n := width - column%width
+			buf.WriteString(strings.Repeat(" ", n))
+			column += n
+			continue
+		case '\n':
+			column = -1
+		}
+		buf.WriteRune(r)
+		column++
+	}
+	return buf.String()
+}